@@ -0,0 +1,206 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+var (
+	aliases    = map[string][]directive{}
+	aliasesMux sync.RWMutex
+)
+
+/*
+RegisterAlias registers a named bundle of directives that expands in place wherever the alias appears in a tag,
+either by name or prefixed with @.
+Aliases may refer to other aliases.
+The directives of the alias must be known at the time of registration,
+and their operators and arguments must be valid for at least one of the types they apply to.
+Registering an alias that is already registered replaces it.
+
+Example:
+
+	RegisterAlias("zip", "required,regexp ^[0-9]{5}$")
+	RegisterAlias("uszip", "@zip,len==5")
+*/
+func RegisterAlias(name string, tags string) error {
+	if name == "" {
+		return fmt.Errorf("invalid alias name '%s'", name)
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return fmt.Errorf("invalid alias name '%s'", name)
+		}
+	}
+	if builtinDirectives[name] || customDirective(name) != nil {
+		return fmt.Errorf("alias '%s' conflicts with a directive", name)
+	}
+	dirs, err := parseTag(tags)
+	if err != nil {
+		return fmt.Errorf("invalid tag of alias '%s': %w", name, err)
+	}
+
+	aliasesMux.Lock()
+	prev, replaced := aliases[name]
+	aliases[name] = dirs
+	expanded, err := expandAliasesLocked(dirs, []string{name})
+	if err == nil {
+		for _, d := range expanded {
+			if !builtinDirectives[d.name] && !isComposite(d) && customDirective(d.name) == nil {
+				err = fmt.Errorf("unknown directive '%s'", d.raw)
+				break
+			}
+			err = checkAliasDirective(d)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		if replaced {
+			aliases[name] = prev
+		} else {
+			delete(aliases, name)
+		}
+		aliasesMux.Unlock()
+		return fmt.Errorf("invalid tag of alias '%s': %w", name, err)
+	}
+	aliasesMux.Unlock()
+	// Plans are reset outside the lock because compiling them expands aliases
+	resetPlans()
+	return nil
+}
+
+// isAlias returns true if an alias with the given name is registered.
+func isAlias(name string) bool {
+	aliasesMux.RLock()
+	defer aliasesMux.RUnlock()
+	_, ok := aliases[name]
+	return ok
+}
+
+// expandAliases replaces aliases with their directives, recursively, including in alternatives and negations.
+// Directives that are not aliases are retained as they are.
+func expandAliases(dirs []directive) ([]directive, error) {
+	aliasesMux.RLock()
+	defer aliasesMux.RUnlock()
+	return expandAliasesLocked(dirs, nil)
+}
+
+// expandAliasesLocked expands the aliases while the lock is held.
+// The stack holds the names of the aliases being expanded, and is used to detect cycles.
+func expandAliasesLocked(dirs []directive, stack []string) ([]directive, error) {
+	found := false
+	for _, d := range dirs {
+		_, ok := aliases[strings.TrimPrefix(d.name, "@")]
+		if ok || strings.HasPrefix(d.name, "@") || isComposite(d) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return dirs, nil
+	}
+	var expanded []directive
+	for _, d := range dirs {
+		if isComposite(d) {
+			// Example: (@zip | len==0)
+			alts := make([][]directive, len(d.alts))
+			for i, alt := range d.alts {
+				var err error
+				alts[i], err = expandAliasesLocked(alt, stack)
+				if err != nil {
+					return nil, err
+				}
+			}
+			d.alts = alts
+			if g, ok := groupedDirective(d); ok {
+				return nil, fmt.Errorf("directive '%s' inside '%s' cannot be limited to groups", g.raw, d.raw)
+			}
+			expanded = append(expanded, d)
+			continue
+		}
+		name := strings.TrimPrefix(d.name, "@")
+		alias, ok := aliases[name]
+		if !ok {
+			if name != d.name {
+				return nil, fmt.Errorf("unknown alias '%s'", d.raw)
+			}
+			expanded = append(expanded, d)
+			continue
+		}
+		if d.op != "" || d.arg != "" {
+			return nil, fmt.Errorf("unexpected argument of alias '%s'", d.raw)
+		}
+		if contains(stack, name) {
+			return nil, fmt.Errorf("cyclic alias '%s'", strings.Join(append(stack, name), "->"))
+		}
+		nested, err := expandAliasesLocked(alias, append(stack[:len(stack):len(stack)], name))
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range nested {
+			// Directives of an alias limited to groups are limited to the same groups, unless limited otherwise
+			if len(n.groups) == 0 {
+				n.groups = d.groups
+			}
+			expanded = append(expanded, n)
+		}
+	}
+	return expanded, nil
+}
+
+// aliasTypes are the types that the directives of an alias are compiled against at the time of registration,
+// along with the directives that apply to each of them.
+var aliasTypes = []struct {
+	refType    reflect.Type
+	applicable []string
+}{
+	{reflect.TypeOf(""), stringDirectives},
+	{reflect.TypeOf(int64(0)), intDirectives},
+	{reflect.TypeOf(uint64(0)), uintDirectives},
+	{reflect.TypeOf(float64(0)), floatDirectives},
+	{reflect.TypeOf(false), boolDirectives},
+	{timeType, timeDirectives},
+	{durationType, durationDirectives},
+	{reflect.TypeOf([]int(nil)), []string{"arrlen"}},
+	{reflect.TypeOf(map[int]int(nil)), []string{"maplen"}},
+}
+
+// checkAliasDirective returns an error if the directive of an alias fails to compile against all the types it applies to.
+// Directives that do not apply to any of these types, such as custom directives, are not checked.
+func checkAliasDirective(d directive) error {
+	var firstErr error
+	for _, t := range aliasTypes {
+		scalar := t.refType.Kind() != reflect.Slice && t.refType.Kind() != reflect.Map
+		if !contains(t.applicable, d.name) && !(isComposite(d) && scalar) {
+			continue
+		}
+		c := &compiler{compiled: map[planKey]*plan{}}
+		err := c.compile(t.refType, []directive{d}).err
+		if err == nil {
+			return nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
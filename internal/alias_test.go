@@ -0,0 +1,127 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlias_Register(t *testing.T) {
+	assert.NoError(t, RegisterAlias("zip5", "required,regexp ^[0-9]{5}$"))
+	assert.NoError(t, RegisterAlias("country", "required,len==2,toupper,default=US"))
+	assert.NoError(t, RegisterAlias("uszip", "@zip5,len==5"))
+
+	assert.ErrorContains(t, RegisterAlias("len", "required"), "conflicts with a directive")
+	assert.ErrorContains(t, RegisterAlias("a-b", "required"), "invalid alias name")
+	assert.ErrorContains(t, RegisterAlias("bad", "requried"), "unknown directive 'requried'")
+	assert.ErrorContains(t, RegisterAlias("bad", "@nosuch"), "unknown alias '@nosuch'")
+	assert.ErrorContains(t, RegisterAlias("bad", "regexp '^[0-9]"), "unterminated quote")
+	assert.ErrorContains(t, RegisterAlias("bad", "@zip5=1"), "unexpected argument of alias '@zip5=1'")
+	assert.ErrorContains(t, RegisterAlias("self", "required,@self"), "cyclic alias 'self->self'")
+	assert.ErrorContains(t, RegisterAlias("bad", "len<=abc"), "invalid syntax")
+	assert.ErrorContains(t, RegisterAlias("bad", "regexp ["), "missing closing ]")
+	assert.ErrorContains(t, RegisterAlias("bad", "val~3"), "unsupported operator '~'")
+	assert.ErrorContains(t, RegisterAlias("bad", "arrlen>x"), "invalid syntax")
+	assert.NoError(t, RegisterAlias("atleasthour", "val>=1h"))
+	assert.NoError(t, RegisterAlias("y2k", "val>=2000-01-01T00:00:00Z"))
+	assert.False(t, isAlias("bad"))
+	assert.False(t, isAlias("self"))
+
+	// Replacing an alias must not introduce a cycle
+	assert.ErrorContains(t, RegisterAlias("zip5", "@uszip"), "cyclic alias 'zip5->uszip->zip5'")
+	dirs, err := expandAliases([]directive{{raw: "@uszip", name: "@uszip"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "required,regexp ^[0-9]{5}$,len==5", joinDirectives(dirs))
+}
+
+func TestAlias_Expand(t *testing.T) {
+	assert.NoError(t, RegisterAlias("postal", "required,regexp ^[0-9]{5}$"))
+	assert.NoError(t, RegisterAlias("nation", "required,len==2,toupper"))
+
+	type address struct {
+		Zip     string `dv8:"@postal"`
+		Zip2    string `dv8:"postal,default=12345"`
+		Country string `dv8:"nation,oneof US|MX"`
+		Bad     string `dv8:"@nosuch"`
+	}
+	a := &address{Zip: "12345", Country: "mx", Bad: "x"}
+	err := Validate(a)
+	assert.ErrorContains(t, err, "Bad: unknown alias '@nosuch'")
+
+	type address2 struct {
+		Zip     string `dv8:"@postal"`
+		Zip2    string `dv8:"postal,default=12345"`
+		Country string `dv8:"nation,oneof US|MX"`
+	}
+	a2 := &address2{Zip: "12345", Country: "mx"}
+	err = Validate(a2)
+	assert.NoError(t, err)
+	assert.Equal(t, "MX", a2.Country)
+	assert.Equal(t, "12345", a2.Zip2)
+
+	a2.Zip = "1234x"
+	err = Validate(a2)
+	assert.ErrorContains(t, err, "Zip: value doesn't match required pattern")
+	a2.Zip = "12345"
+	a2.Country = "ca"
+	err = Validate(a2)
+	assert.ErrorContains(t, err, "Country: value must be one of US|MX")
+
+	// Changes to an alias apply to tags that refer to it
+	assert.NoError(t, RegisterAlias("postal", "required,regexp ^[0-9]{4}$"))
+	err = Validate(&address2{Zip: "1234", Zip2: "1234", Country: "US"})
+	assert.NoError(t, err)
+
+	err = Check(reflect.TypeOf(address{}))
+	assert.ErrorContains(t, err, "invalid tag of field 'Bad'")
+}
+
+func TestAlias_Composite(t *testing.T) {
+	assert.NoError(t, RegisterAlias("reserved", "!oneof admin|root"))
+	assert.NoError(t, RegisterAlias("zip5x", "regexp ^[0-9]{5}$"))
+	assert.NoError(t, RegisterAlias("optzip", "(@zip5x | len==0)"))
+	assert.ErrorContains(t, RegisterAlias("bad", "(len==0 | regexp [)"), "missing closing ]")
+	assert.ErrorContains(t, RegisterAlias("bad", "!@nosuch"), "unknown alias '@nosuch'")
+
+	x := struct {
+		User string `dv8:"@reserved"`
+		Zip  string `dv8:"(@zip5x | len==0)"`
+		Opt  string `dv8:"@optzip"`
+		Not  string `dv8:"!@zip5x"`
+	}{
+		User: "jane",
+		Zip:  "12345",
+		Not:  "abc",
+	}
+	err := ValidateOptions(context.Background(), &x, Options{Strict: true})
+	assert.NoError(t, err)
+
+	x.User = "root"
+	x.Zip = "123"
+	x.Opt = "123"
+	x.Not = "12345"
+	err = ValidateOptions(context.Background(), &x, Options{Strict: true, All: true})
+	if assert.Len(t, err, 4) {
+		assert.ErrorContains(t, err, "User: value must not satisfy 'oneof admin|root'")
+		assert.ErrorContains(t, err, "Zip: value must satisfy one of 'regexp ^[0-9]{5}$' (value doesn't match required pattern) or 'len==0'")
+		assert.ErrorContains(t, err, "Opt: value must satisfy one of")
+		assert.ErrorContains(t, err, "Not: value must not satisfy 'regexp ^[0-9]{5}$'")
+	}
+}
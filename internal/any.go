@@ -16,70 +16,101 @@ limitations under the License.
 package internal
 
 import (
-	"context"
+	"errors"
 	"reflect"
 )
 
-// validateAny validates the value of any type against the tags.
-func validateAny(ctx context.Context, refType reflect.Type, refVal reflect.Value, tags []string) (err error) {
-	switch refType.String() {
-	case "time.Duration":
-		err = validateDuration(refVal, tags)
-	case "time.Time":
-		err = validateTime(refVal, tags)
-	default:
-		switch refType.Kind() {
-		case reflect.String:
-			err = validateString(refVal, tags)
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			err = validateInt(refVal, tags)
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			err = validateUint(refVal, tags)
-		case reflect.Float32, reflect.Float64:
-			err = validateFloat(refVal, tags)
-		case reflect.Bool:
-			err = validateBool(refVal, tags)
-		case reflect.Pointer:
-			err = validatePointer(ctx, refType, refVal, tags)
-		case reflect.Struct:
-			err = validateStruct(ctx, refType, refVal, tags)
-		case reflect.Map:
-			err = validateMap(ctx, refType, refVal, tags)
-		case reflect.Array, reflect.Slice:
-			err = validateArray(ctx, refType, refVal, tags)
-		}
+// validateAny validates the value of any type against its plan.
+func validateAny(w *walk, p *plan, refVal reflect.Value) (err error) {
+	if p.err != nil {
+		return p.err
+	}
+	original := refVal
+	switch p.kind {
+	case kindScalar:
+		refVal, err = validateScalar(w, p, refVal)
+	case kindPointer:
+		err = validatePointer(w, p, refVal)
+	case kindStruct:
+		err = validateStruct(w, p, refVal)
+	case kindMap:
+		err = validateMap(w, p, refVal)
+	case kindArray:
+		err = validateArray(w, p, refVal)
+	case kindInterface:
+		err = validateInterface(w, p, refVal)
+	case kindType:
+		err = validateType(w, p, refVal)
 	}
 	if err != nil {
 		return err
 	}
+	if w.normalizing {
+		return nil
+	}
+	err = validateCustom(w, p, refVal)
+	if err != nil {
+		return err
+	}
 
 	// Call the type's Validate method, if implemented
-	var ok bool
-	var okCtx bool
-	var validator Validator
-	var validatorCtx ValidatorContext
-	if refVal.CanAddr() {
-		underlyingPtr := refVal.Addr().Interface()
-		validator, ok = underlyingPtr.(Validator)
-		validatorCtx, okCtx = underlyingPtr.(ValidatorContext)
+	if w.opts.DryRun && p.hasValidators() {
+		// The normalized values were not set, so the validators are called on a normalized copy, which they may modify
+		refVal = normalizedCopy(w, p, original)
 	}
-	if !ok {
-		underlying := refVal.Interface()
-		validator, ok = underlying.(Validator)
-		validatorCtx, okCtx = underlying.(ValidatorContext)
+	return callValidators(w.ctx, p, refVal)
+}
+
+// validateScalar normalizes the value of a string, number, bool, time or duration,
+// then validates it against the constraints of its plan.
+// It returns the normalized value, which in dry run mode is computed without being set.
+func validateScalar(w *walk, p *plan, refVal reflect.Value) (normalized reflect.Value, err error) {
+	val := refVal
+	changed := false
+	var changes []Change
+	for _, n := range p.normalizers {
+		if normalized, ok := n.apply(val); ok {
+			if w.report {
+				changes = append(changes, Change{
+					Directive: n.directive,
+					Old:       valueOf(val),
+					New:       valueOf(normalized),
+				})
+			}
+			val = normalized
+			changed = true
+		}
 	}
-	if ok {
-		err = validator.Validate()
-		if err != nil {
-			return err
+	if changed && !w.opts.DryRun {
+		if !refVal.CanSet() {
+			return refVal, errors.New("data must be passed by reference")
 		}
+		w.set(refVal, val)
+		val = refVal
 	}
-	if okCtx {
-		err = validatorCtx.ValidateContext(ctx)
+	w.recordChanges(changes)
+	if p.optional && val.IsZero() {
+		return val, nil
+	}
+	for _, c := range p.constraints {
+		err = c.check(val)
 		if err != nil {
-			return err
+			return val, &FieldError{
+				Directive: c.directive,
+				Operator:  c.operator,
+				Param:     c.param,
+				Value:     valueOf(val),
+				Err:       err,
+			}
 		}
 	}
+	return val, nil
+}
 
-	return nil
+// readOnly returns a copy of the value that cannot be set.
+func readOnly(refVal reflect.Value) reflect.Value {
+	if !refVal.CanSet() {
+		return refVal
+	}
+	return reflect.ValueOf(refVal.Interface()).Convert(refVal.Type())
 }
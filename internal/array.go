@@ -16,65 +16,108 @@ limitations under the License.
 package internal
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"reflect"
-	"strconv"
-	"strings"
 )
 
-// validateArray validates the value of an array against the tags.
-func validateArray(ctx context.Context, refType reflect.Type, refVal reflect.Value, tags []string) (err error) {
-	// Length
-	for i, t := range tags {
-		if strings.HasPrefix(t, "arrlen") && len(t) > 7 {
-			if refVal.IsNil() {
-				return errors.New("value is required")
-			}
-			// Example: arrlen<8
-			operator := t[6:7]
-			var l int
-			if t[7] == '=' {
-				operator += "="
-				l, err = strconv.Atoi(t[8:])
-			} else {
-				l, err = strconv.Atoi(t[7:])
-			}
-			if err != nil {
-				return err
-			}
-			arrayLen := refVal.Len()
-			switch {
-			case operator == "<=" && arrayLen > l:
-				err = fmt.Errorf("length must be less than or equal to %d", l)
-			case operator == "<" && arrayLen >= l:
-				err = fmt.Errorf("length must be less than %d", l)
-			case operator == ">=" && arrayLen < l:
-				err = fmt.Errorf("length must be greater than or equal to %d", l)
-			case operator == ">" && arrayLen <= l:
-				err = fmt.Errorf("length must be greater than %d", l)
-			case operator == "!=" && arrayLen == l:
-				err = fmt.Errorf("length must not equal %d", l)
-			case operator == "==" && arrayLen != l:
-				err = fmt.Errorf("length must equal %d", l)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
+// compileArray compiles the plan of an array and of its items.
+// Except for arrlen, nonnil and nullable, directives set on an array apply to its items.
+// A required array must also be non-empty.
+func (c *compiler) compileArray(p *plan, dirs []directive) error {
+	var errs Errors
+	p.required = hasDirective(dirs, "required")
+	var itemDirs []directive
+	for _, d := range dirs {
+		if contains(nilDirectives, d.name) {
+			continue
+		}
+		if d.name != "arrlen" {
+			itemDirs = append(itemDirs, d)
+			continue
+		}
+		// Example: arrlen<8
+		cons, err := compileLen(d, reflect.Value.Len)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.constraints = append(p.constraints, cons)
+	}
+	p.elem = c.compile(p.refType.Elem(), itemDirs)
+	if c.opts.strict {
+		errs = appendUnique(errs, p.elem.err)
+	}
+	return errs.join()
+}
+
+// validateArray validates the value of an array against its plan.
+func validateArray(w *walk, p *plan, refVal reflect.Value) (err error) {
+	var errs Errors
+	// Nil and length
+	isNil := refVal.Kind() == reflect.Slice && refVal.IsNil()
+	if isNil {
+		err = validateNil(p, refVal)
+		if err != nil {
+			return err
+		}
+	}
+	if p.required && refVal.Len() == 0 {
+		return &FieldError{
+			Directive: "required",
+			Value:     valueOf(refVal),
+			Err:       errors.New("non-empty value is required"),
+		}
+	}
+	constraints := p.constraints
+	if p.optional && refVal.Len() == 0 || p.nullable && isNil {
+		// Empty optional arrays and nil nullable arrays are not constrained
+		constraints = nil
+	}
+	if len(constraints) > 0 && isNil {
+		return &FieldError{
+			Directive: "arrlen",
+			Value:     valueOf(refVal),
+			Err:       errors.New("value is required"),
+		}
+	}
+	for _, c := range constraints {
+		err = c.check(refVal)
+		if err != nil {
+			err = &FieldError{
+				Directive: c.directive,
+				Operator:  c.operator,
+				Param:     c.param,
+				Value:     valueOf(refVal),
+				Err:       err,
 			}
-			if err != nil {
+			if !w.opts.All {
 				return err
 			}
-			tags[i] = "" // Do not apply to items
+			errs = errs.collect(err)
 		}
 	}
 	// Nested elements
-	arrayType := refType.Elem()
+	sel := w.sel
+	defer func() { w.sel = sel }()
 	for j := 0; j < refVal.Len(); j++ {
+		if !sel.whole() {
+			// Only the selected items are validated
+			w.sel = sel.item(j)
+			if w.sel == nil {
+				continue
+			}
+		}
 		val := refVal.Index(j)
-		err = validateAny(ctx, arrayType, val, tags)
+		w.push(PathSegment{Index: j})
+		err = validateAny(w, p.elem, val)
+		w.pop()
 		if err != nil {
-			return fmt.Errorf("[%d]: %w", j, err)
+			err = atPath(PathSegment{Index: j}, err)
+			if !w.opts.All {
+				return err
+			}
+			errs = errs.collect(err)
 		}
 	}
-	return nil
+	return errs.orNil()
 }
@@ -23,7 +23,7 @@ import (
 
 func TestArray_Pointer(t *testing.T) {
 	x := struct {
-		A *[]int `dv8:"required"`
+		A *[]int `dv8:"nonnil"`
 	}{}
 	a := []int{}
 	x.A = &a
@@ -32,7 +32,18 @@ func TestArray_Pointer(t *testing.T) {
 
 	x.A = nil
 	err = Validate(&x)
-	assert.ErrorContains(t, err, "required")
+	assert.ErrorContains(t, err, "non-nil value is required")
+
+	y := struct {
+		A *[]int `dv8:"required"`
+	}{}
+	y.A = &a
+	err = Validate(&y)
+	assert.ErrorContains(t, err, "non-empty value is required")
+
+	a = append(a, 1)
+	err = Validate(&y)
+	assert.NoError(t, err)
 }
 
 func TestArray_Nesting(t *testing.T) {
@@ -174,3 +185,52 @@ func TestArray_Items(t *testing.T) {
 	assert.ErrorContains(t, err, "length")
 	assert.ErrorContains(t, err, "[1]")
 }
+
+func TestArray_Optional(t *testing.T) {
+	x := struct {
+		A []string `dv8:"optional,arrlen>=2,len==2"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.A = []string{}
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.A = []string{"CA"}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "length must be greater than or equal to 2")
+
+	// The items are optional as well
+	x.A = []string{"CA", ""}
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.A = []string{"CA", "Cal"}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "length must equal 2")
+}
+
+func TestArray_NilDirectives(t *testing.T) {
+	x := struct {
+		NonNil   []int `dv8:"nonnil"`
+		Nullable []int `dv8:"nullable,arrlen>=1"`
+	}{
+		NonNil: []int{},
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.NonNil = nil
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "NonNil: non-nil value is required")
+
+	x.NonNil = []int{}
+	x.Nullable = []int{}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Nullable: length must be greater than or equal to 1")
+
+	x.Nullable = []int{1}
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
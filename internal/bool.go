@@ -16,68 +16,79 @@ limitations under the License.
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 )
 
-// validateBool validates the value of a boolean against the tags.
-func validateBool(refVal reflect.Value, tags []string) (err error) {
-	b := refVal.Bool()
+// boolDirectives are the directives applicable to a boolean.
+var boolDirectives = []string{"required", "optional", "default", "val"}
+
+// compileBool compiles the directives that apply to a boolean.
+func compileBool(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if !b && strings.HasPrefix(t, "default=") {
-			def, err := strconv.ParseBool(t[len("default="):])
+		case "default":
+			def, err := strconv.ParseBool(d.arg)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if def != b {
-				b = def
-				changed = true
+			if !def {
+				continue
 			}
+			defVal := reflect.ValueOf(def).Convert(p.refType)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.Bool() {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetBool(b)
-	}
-	if !b && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("non-zero value is required", reflect.Value.IsZero))
 	}
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<M
-			operator := t[3:4]
-			var v bool
-			if t[4] == '=' {
-				operator += "="
-				v, err = strconv.ParseBool(t[5:])
-			} else {
-				v, err = strconv.ParseBool(t[4:])
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "!=" && b == v:
-				err = fmt.Errorf("must not equal %v", v)
-			case operator == "==" && b != v:
-				err = fmt.Errorf("must equal %v", v)
-			case operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val==true
+		operator := d.op
+		v, err := strconv.ParseBool(d.arg)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				b := refVal.Bool()
+				switch {
+				case operator == "!=" && b == v:
+					return fmt.Errorf("must not equal %v", v)
+				case operator == "==" && b != v:
+					return fmt.Errorf("must equal %v", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
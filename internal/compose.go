@@ -0,0 +1,117 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// isComposite returns true if the directive is a group of alternatives or a negated directive.
+func isComposite(d directive) bool {
+	return d.name == "(" || d.name == "!"
+}
+
+// plain returns the directives that are not composite.
+func plain(dirs []directive) []directive {
+	var result []directive
+	for _, d := range dirs {
+		if !isComposite(d) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+/*
+compileComposites compiles the groups of alternatives and the negated directives of a scalar into constraints.
+Each alternative is compiled as a plan of its own, of which only the constraints are checked.
+
+Example:
+
+	(len==0 | regexp ^[0-9]{5}$)
+	!oneof admin|root
+	!(val>=10 | val<=-10)
+*/
+func (c *compiler) compileComposites(p *plan, dirs []directive) error {
+	var errs Errors
+	for _, d := range dirs {
+		if !isComposite(d) {
+			continue
+		}
+		var alts []*plan
+		for _, altDirs := range d.alts {
+			alt := c.compile(p.refType, altDirs)
+			if alt.err != nil {
+				errs = append(errs, alt.err)
+				continue
+			}
+			if len(alt.constraints) == 0 || len(alt.custom) > 0 {
+				errs = append(errs, fmt.Errorf("directive '%s' cannot be composed", joinDirectives(altDirs)))
+				continue
+			}
+			alts = append(alts, alt)
+		}
+		if len(alts) < len(d.alts) {
+			continue
+		}
+		if d.name == "!" {
+			// Example: !oneof admin|root
+			negated := joinDirectives(d.alts[0])
+			p.constraints = append(p.constraints, constraint{
+				directive: "!",
+				param:     negated,
+				check: func(refVal reflect.Value) error {
+					if checkConstraints(alts[0], refVal) == nil {
+						return fmt.Errorf("value must not satisfy '%s'", negated)
+					}
+					return nil
+				},
+			})
+			continue
+		}
+		// Example: (len==0 | regexp ^[0-9]{5}$)
+		p.constraints = append(p.constraints, constraint{
+			directive: "(",
+			param:     d.raw,
+			check: func(refVal reflect.Value) error {
+				var failed []string
+				for i, alt := range alts {
+					err := checkConstraints(alt, refVal)
+					if err == nil {
+						return nil
+					}
+					failed = append(failed, fmt.Sprintf("'%s' (%v)", joinDirectives(d.alts[i]), err))
+				}
+				return errors.New("value must satisfy one of " + strings.Join(failed, " or "))
+			},
+		})
+	}
+	return errs.join()
+}
+
+// checkConstraints returns the error of the first constraint of the plan that the value fails.
+func checkConstraints(p *plan, refVal reflect.Value) error {
+	for _, c := range p.constraints {
+		err := c.check(refVal)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
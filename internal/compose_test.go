@@ -0,0 +1,193 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompose_Alternatives(t *testing.T) {
+	x := struct {
+		Zip string `dv8:"(len==0 | regexp ^[0-9]{5}$)"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.Zip = "12345"
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.Zip = "1234"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Zip: value must satisfy one of 'len==0' (length must equal 0) or 'regexp ^[0-9]{5}$' (value doesn't match required pattern)")
+}
+
+func TestCompose_Negation(t *testing.T) {
+	x := struct {
+		User string `dv8:"tolower,!oneof admin|root"`
+	}{
+		User: "jane",
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.User = "Admin"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "User: value must not satisfy 'oneof admin|root'")
+	var fe *FieldError
+	if assert.ErrorAs(t, err, &fe) {
+		assert.Equal(t, "!", fe.Directive)
+		assert.Equal(t, "oneof admin|root", fe.Param)
+		assert.Equal(t, "admin", fe.Value)
+	}
+}
+
+func TestCompose_AllOfAlternative(t *testing.T) {
+	x := struct {
+		Code string `dv8:"(len==0 | len==6,regexp ^[A-Z]+$)"`
+	}{
+		Code: "ABCDEF",
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.Code = "ABC123"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "'len==6,regexp ^[A-Z]+$' (value doesn't match required pattern)")
+}
+
+func TestCompose_Nested(t *testing.T) {
+	x := struct {
+		N int `dv8:"!(val>=10 | val<=-10)"`
+	}{
+		N: 5,
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.N = 10
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "value must not satisfy '(val>=10 | val<=-10)'")
+
+	y := struct {
+		N int `dv8:"(val==0 | !(val>-10,val<10))"`
+	}{}
+	err = Validate(&y)
+	assert.NoError(t, err)
+	y.N = 100
+	err = Validate(&y)
+	assert.NoError(t, err)
+	y.N = 5
+	err = Validate(&y)
+	assert.Error(t, err)
+}
+
+func TestCompose_Types(t *testing.T) {
+	x := struct {
+		F float64       `dv8:"(val<0 | val>1)"`
+		T time.Time     `dv8:"!val<2000-01-01T00:00:00Z"`
+		D time.Duration `dv8:"(val==0 | val>=1s)"`
+		U []uint        `dv8:"!val==13"`
+	}{
+		F: 2,
+		T: mustParseTime("2024-01-01"),
+		D: time.Minute,
+		U: []uint{1, 2},
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.F = 0.5
+	x.T = mustParseTime("1999-12-31")
+	x.D = time.Millisecond
+	x.U = []uint{1, 13}
+	err = ValidateAll(context.Background(), &x)
+	if assert.Error(t, err) {
+		assert.ErrorContains(t, err, "F: value must satisfy one of")
+		assert.ErrorContains(t, err, "T: value must not satisfy 'val<2000-01-01T00:00:00Z'")
+		assert.ErrorContains(t, err, "D: value must satisfy one of")
+		assert.ErrorContains(t, err, "[1]: value must not satisfy 'val==13'")
+	}
+}
+
+func TestCompose_Groups(t *testing.T) {
+	x := struct {
+		User string `dv8:"!oneof 'admin|root'@create"`
+	}{
+		User: "root",
+	}
+	err := ValidateOptions(context.Background(), &x, Options{Groups: []string{"update"}})
+	assert.NoError(t, err)
+	err = ValidateOptions(context.Background(), &x, Options{Groups: []string{"create"}})
+	assert.ErrorContains(t, err, "must not satisfy")
+
+	y := struct {
+		Zip string `dv8:"(len==0 | len==5)@create"`
+	}{
+		Zip: "123",
+	}
+	err = ValidateOptions(context.Background(), &y, Options{Groups: []string{"update"}})
+	assert.NoError(t, err)
+	err = ValidateOptions(context.Background(), &y, Options{Groups: []string{"create"}})
+	assert.ErrorContains(t, err, "must satisfy one of")
+
+	// Directives inside alternatives cannot be limited to groups
+	z := struct {
+		Zip string `dv8:"(required@create | len==5)"`
+	}{}
+	err = Check(reflect.TypeOf(z))
+	assert.ErrorContains(t, err, "directive 'required' inside '(required@create | len==5)'")
+	err = ValidateOptions(context.Background(), &z, Options{Groups: []string{"update"}})
+	assert.ErrorContains(t, err, "cannot be limited to groups")
+
+	assert.NoError(t, RegisterAlias("createonly", "len==5@create"))
+	w := struct {
+		Zip string `dv8:"(len==0 | @createonly)"`
+	}{}
+	err = Check(reflect.TypeOf(w))
+	assert.ErrorContains(t, err, "directive 'len==5' inside '(len==0 | @createonly)' cannot be limited to groups")
+}
+
+func TestCompose_CompileErrors(t *testing.T) {
+	x1 := struct {
+		S string `dv8:"(len==0 | regexp [)"`
+	}{}
+	err := Validate(&x1)
+	assert.ErrorContains(t, err, "missing closing ]")
+
+	x2 := struct {
+		S string `dv8:"!toupper"`
+	}{}
+	err = Validate(&x2)
+	assert.ErrorContains(t, err, "directive 'toupper' cannot be composed")
+
+	x3 := struct {
+		S struct{ A int } `dv8:"!required"`
+	}{}
+	err = Check(reflect.TypeOf(x3))
+	assert.ErrorContains(t, err, "directive '!required' is not applicable")
+
+	x4 := struct {
+		S string `dv8:"(len==0 | arrlen>0)"`
+	}{}
+	err = Check(reflect.TypeOf(x4))
+	assert.ErrorContains(t, err, "directive 'arrlen>0' is not applicable")
+}
@@ -0,0 +1,191 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// conditionalDirectives are the directives that require a field depending on its sibling fields.
+var conditionalDirectives = []string{"required_if", "required_unless", "required_with", "required_without"}
+
+// condition is a compiled directive that references sibling fields, e.g. "required_if=Country:US" or "gtfield=Start".
+type condition struct {
+	directive
+	check func(refVal reflect.Value, structVal reflect.Value) error
+}
+
+/*
+compileConditions compiles the directives of a field of a struct that reference its sibling fields,
+namely conditional requirements and comparisons.
+It returns the remaining directives.
+
+Example:
+
+	required_if=Country:US|MX
+	required_unless=Country:US
+	required_with=Phone|Fax
+	required_without=Email
+	val>=Field(Start)
+	eqfield=Password
+*/
+func compileConditions(refType reflect.Type, fldType reflect.Type, dirs []directive) (conds []condition, rest []directive, err error) {
+	var errs Errors
+	for _, d := range dirs {
+		if isFieldComparison(d) {
+			cond, err := compileFieldComparison(refType, fldType, d)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			conds = append(conds, cond)
+			continue
+		}
+		if !contains(conditionalDirectives, d.name) {
+			rest = append(rest, d)
+			continue
+		}
+		switch d.op {
+		case "", "=":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", d.op)))
+			continue
+		}
+		name := d.arg
+		var vals []string
+		if d.name == "required_if" || d.name == "required_unless" {
+			// Example: required_if=Country:US|MX
+			var list string
+			var ok bool
+			name, list, ok = strings.Cut(d.arg, ":")
+			if !ok {
+				errs = append(errs, compileError(d, errors.New("expected field name and values separated by ':'")))
+				continue
+			}
+			vals = splitArg(list, '|')
+		}
+		// Example: required_with=Phone|Fax
+		names := splitArg(name, '|')
+		if len(vals) > 0 && len(names) > 1 {
+			errs = append(errs, compileError(d, errors.New("expected a single field name")))
+			continue
+		}
+		var indexes [][]int
+		for _, n := range names {
+			fld, ok := refType.FieldByName(n)
+			if !ok {
+				errs = append(errs, compileError(d, fmt.Errorf("field '%s' not found in '%v'", n, refType)))
+				break
+			}
+			indexes = append(indexes, fld.Index)
+		}
+		if len(indexes) < len(names) {
+			continue
+		}
+		cond := condition{directive: d}
+		switch d.name {
+		case "required_if", "required_unless":
+			msg := fmt.Sprintf("value is required when %s is %s", names[0], strings.Join(vals, " or "))
+			want := d.name == "required_if"
+			if !want {
+				msg = fmt.Sprintf("value is required unless %s is %s", names[0], strings.Join(vals, " or "))
+			}
+			cond.check = func(refVal reflect.Value, structVal reflect.Value) error {
+				if !refVal.IsZero() {
+					return nil
+				}
+				sibling := fmt.Sprintf("%v", valueOf(indirect(siblingOf(structVal, indexes[0]))))
+				if contains(vals, sibling) == want {
+					return errors.New(msg)
+				}
+				return nil
+			}
+		case "required_with", "required_without":
+			msg := fmt.Sprintf("value is required when %s is present", strings.Join(names, " or "))
+			want := true
+			if d.name == "required_without" {
+				msg = fmt.Sprintf("value is required when %s is absent", strings.Join(names, " or "))
+				want = false
+			}
+			cond.check = func(refVal reflect.Value, structVal reflect.Value) error {
+				if !refVal.IsZero() {
+					return nil
+				}
+				for _, index := range indexes {
+					if !siblingOf(structVal, index).IsZero() == want {
+						return errors.New(msg)
+					}
+				}
+				return nil
+			}
+		}
+		conds = append(conds, cond)
+	}
+	return conds, rest, errs.join()
+}
+
+// siblingOf returns the field of the struct at the index,
+// or its zero value if the field is promoted through a nil embedded pointer.
+func siblingOf(structVal reflect.Value, index []int) reflect.Value {
+	fld, err := structVal.FieldByIndexErr(index)
+	if err != nil {
+		return reflect.Zero(structVal.Type().FieldByIndex(index).Type)
+	}
+	return fld
+}
+
+// indirect returns the target of a pointer, or the value itself if it is not a pointer.
+func indirect(refVal reflect.Value) reflect.Value {
+	for refVal.Kind() == reflect.Pointer && !refVal.IsNil() {
+		refVal = refVal.Elem()
+	}
+	return refVal
+}
+
+// validateConditions validates the field against the directives that reference its sibling fields.
+func validateConditions(fld *fieldPlan, structVal reflect.Value) error {
+	refVal := structVal.FieldByIndex(fld.index)
+	skipComparisons := optionalZero(fld.plan, refVal)
+	for _, c := range fld.conds {
+		if skipComparisons && isFieldComparison(c.directive) {
+			// Optional zero values are not compared to their sibling fields, but may still be required
+			continue
+		}
+		err := c.check(refVal, structVal)
+		if err != nil {
+			return &FieldError{
+				Directive: c.name,
+				Operator:  c.op,
+				Param:     c.arg,
+				Value:     valueOf(refVal),
+				Err:       err,
+			}
+		}
+	}
+	return nil
+}
+
+// optionalZero returns true if the value is optional and zero, or points to such a value.
+func optionalZero(p *plan, refVal reflect.Value) bool {
+	for p.kind == kindPointer && !refVal.IsNil() {
+		p = p.elem
+		refVal = refVal.Elem()
+	}
+	return p.optional && refVal.IsZero()
+}
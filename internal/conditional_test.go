@@ -0,0 +1,151 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditional_RequiredIf(t *testing.T) {
+	x := struct {
+		State   string `dv8:"required_if=Country:US|MX"`
+		Zip     *int   `dv8:"required_unless=Country:XX"`
+		Country string `dv8:"toupper"`
+	}{
+		Country: "us",
+	}
+	err := Validate(&x)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "State: value is required when Country is US or MX", fe.Error())
+		assert.Equal(t, "required_if", fe.Directive)
+		assert.Equal(t, "Country:US|MX", fe.Param)
+	}
+
+	x.State = "CA"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Zip: value is required unless Country is XX")
+
+	x.Country = "XX"
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.State = ""
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
+
+func TestConditional_RequiredWith(t *testing.T) {
+	x := struct {
+		Email string   `dv8:"required_without=Phone"`
+		Phone string   `dv8:"required_without=Email"`
+		Ext   string   `dv8:"required_with=Phone|Fax"`
+		Fax   []string `dv8:"required_with=Ext"`
+	}{}
+	err := ValidateAll(context.Background(), &x)
+	if assert.Error(t, err) {
+		assert.Len(t, err.(Errors), 2)
+		assert.ErrorContains(t, err.(Errors)[0], "Email: value is required when Phone is absent")
+		assert.ErrorContains(t, err.(Errors)[1], "Phone: value is required when Email is absent")
+	}
+
+	x.Phone = " 555-1234 "
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Ext: value is required when Phone or Fax is present")
+
+	x.Ext = "12"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Fax: value is required when Ext is present")
+
+	x.Fax = []string{"555-4321"}
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	// Evaluated after normalization
+	x.Phone = "  "
+	x.Email = "jane@example.com"
+	x.Ext = ""
+	x.Fax = nil
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
+
+func TestConditional_Errors(t *testing.T) {
+	x := struct {
+		A string `dv8:"required_if=Nope:1"`
+		B string `dv8:"required_with=A|Nope"`
+		C string `dv8:"required_if=A"`
+		D string `dv8:"required_if=A|B:1"`
+		E string `dv8:"required_with<A"`
+	}{}
+	err := Validate(&x)
+	assert.ErrorContains(t, err, "field 'Nope' not found")
+
+	err = Check(reflect.TypeOf(x))
+	if assert.Error(t, err) {
+		errs := err.(Errors)
+		assert.Len(t, errs, 5)
+		assert.ErrorContains(t, errs[0], "field 'Nope' not found")
+		assert.ErrorContains(t, errs[1], "field 'Nope' not found")
+		assert.ErrorContains(t, errs[2], "separated by ':'")
+		assert.ErrorContains(t, errs[3], "single field name")
+		assert.ErrorContains(t, errs[4], "unsupported operator")
+	}
+}
+
+type conditionalEmbedded struct {
+	Zip     string
+	Country string
+}
+
+func TestConditional_NilEmbedded(t *testing.T) {
+	x := struct {
+		*conditionalEmbedded
+		With   string `dv8:"required_with=Zip"`
+		If     string `dv8:"required_if=Country:US"`
+		Unless string `dv8:"required_unless=Country:US"`
+	}{}
+	err := ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 1) {
+		assert.ErrorContains(t, err, "Unless: value is required unless Country is US")
+	}
+
+	x.conditionalEmbedded = &conditionalEmbedded{Zip: "12345", Country: "US"}
+	err = ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 2) {
+		assert.ErrorContains(t, err, "With: value is required when Zip is present")
+		assert.ErrorContains(t, err, "If: value is required when Country is US")
+	}
+}
+
+func TestConditional_DryRun(t *testing.T) {
+	x := struct {
+		Country string `dv8:"default=US"`
+		State   string `dv8:"required_if=Country:US"`
+	}{}
+	err := ValidateOptions(context.Background(), &x, Options{DryRun: true})
+	assert.ErrorContains(t, err, "State: value is required when Country is US")
+	assert.Equal(t, "", x.Country)
+
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "State: value is required when Country is US")
+	assert.Equal(t, "US", x.Country)
+}
@@ -0,0 +1,130 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"reflect"
+)
+
+// DeepCopy returns a copy of the data that shares no pointers, slices, maps or interfaces with it,
+// so that normalizing the copy does not modify the original.
+// Unexported fields, functions and channels are copied shallowly.
+func DeepCopy(data any) any {
+	if data == nil {
+		return nil
+	}
+	c := copier{visited: map[copyKey]reflect.Value{}}
+	return c.copy(reflect.ValueOf(data)).Interface()
+}
+
+// copyKey identifies a pointer that was already copied.
+type copyKey struct {
+	ptr     uintptr
+	refType reflect.Type
+}
+
+// copier deep copies values, preserving pointers that point to the same target, including cyclic ones.
+type copier struct {
+	visited map[copyKey]reflect.Value
+}
+
+// copy returns a deep copy of the value.
+func (c *copier) copy(refVal reflect.Value) reflect.Value {
+	switch refVal.Kind() {
+	case reflect.Pointer:
+		if refVal.IsNil() {
+			return refVal
+		}
+		key := copyKey{ptr: refVal.Pointer(), refType: refVal.Type()}
+		if cp, ok := c.visited[key]; ok {
+			return cp
+		}
+		cp := reflect.New(refVal.Type().Elem())
+		c.visited[key] = cp
+		cp.Elem().Set(c.copy(refVal.Elem()))
+		return cp
+	case reflect.Interface:
+		if refVal.IsNil() {
+			return refVal
+		}
+		cp := reflect.New(refVal.Type()).Elem()
+		cp.Set(c.copy(refVal.Elem()))
+		return cp
+	case reflect.Struct:
+		cp := reflect.New(refVal.Type()).Elem()
+		cp.Set(refVal)
+		for i := 0; i < cp.NumField(); i++ {
+			fld := cp.Field(i)
+			if fld.CanSet() {
+				fld.Set(c.copy(refVal.Field(i)))
+			}
+		}
+		return cp
+	case reflect.Array:
+		cp := reflect.New(refVal.Type()).Elem()
+		for i := 0; i < refVal.Len(); i++ {
+			cp.Index(i).Set(c.copy(refVal.Index(i)))
+		}
+		return cp
+	case reflect.Slice:
+		if refVal.IsNil() {
+			return refVal
+		}
+		cp := reflect.MakeSlice(refVal.Type(), refVal.Len(), refVal.Len())
+		for i := 0; i < refVal.Len(); i++ {
+			cp.Index(i).Set(c.copy(refVal.Index(i)))
+		}
+		return cp
+	case reflect.Map:
+		if refVal.IsNil() {
+			return refVal
+		}
+		cp := reflect.MakeMapWithSize(refVal.Type(), refVal.Len())
+		iter := refVal.MapRange()
+		for iter.Next() {
+			cp.SetMapIndex(iter.Key(), c.copy(iter.Value()))
+		}
+		return cp
+	default:
+		return refVal
+	}
+}
+
+/*
+normalizedCopy returns a copy of the value with the normalizations of its plan applied,
+as they would have been applied if not in dry run mode.
+Custom directives and validators are not called, and constraints are not enforced.
+Values that cannot be copied because they were obtained from unexported fields are returned as they are.
+*/
+func normalizedCopy(w *walk, p *plan, refVal reflect.Value) reflect.Value {
+	if !refVal.CanInterface() {
+		return refVal
+	}
+	c := copier{visited: map[copyKey]reflect.Value{}}
+	cp := reflect.New(refVal.Type()).Elem()
+	cp.Set(c.copy(refVal))
+	nw := &walk{
+		ctx:         w.ctx,
+		opts:        w.opts,
+		normalizing: true,
+	}
+	nw.opts.DryRun = false
+	nw.opts.Atomic = false
+	nw.opts.All = true
+	nw.opts.Fields = nil
+	_ = validateAny(nw, p, cp)
+	return cp
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopy_Deep(t *testing.T) {
+	type node struct {
+		Name  string `dv8:"toupper"`
+		Next  *node
+		Tags  []string          `dv8:"tolower"`
+		Attrs map[string]string `dv8:"tolower"`
+		Any   any               `dv8:"toupper"`
+		Arr   [2]*Animal
+		priv  *struct{ N int }
+	}
+	x := &node{
+		Name:  "a",
+		Tags:  []string{"FOO"},
+		Attrs: map[string]string{"k": "BAR"},
+		Any:   "baz",
+		Arr:   [2]*Animal{{Name: "Zebra"}, nil},
+		priv:  &struct{ N int }{N: 1},
+	}
+	x.Next = &node{Tags: []string{"FOO"}}
+
+	cp := DeepCopy(x).(*node)
+	err := Validate(cp)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "A", cp.Name)
+	assert.Equal(t, "foo", cp.Next.Tags[0])
+	assert.Equal(t, "foo", cp.Tags[0])
+	assert.Equal(t, "bar", cp.Attrs["k"])
+	assert.Equal(t, "BAZ", cp.Any)
+	assert.Equal(t, "Mammal", cp.Arr[0].Kind)
+	assert.Nil(t, cp.Arr[1])
+	assert.True(t, cp.priv == x.priv) // Unexported fields are copied shallowly
+
+	assert.Equal(t, "a", x.Name)
+	assert.Equal(t, "FOO", x.Tags[0])
+	assert.Equal(t, "FOO", x.Next.Tags[0])
+	assert.Equal(t, "BAR", x.Attrs["k"])
+	assert.Equal(t, "baz", x.Any)
+	assert.Equal(t, "", x.Arr[0].Kind)
+
+	assert.Nil(t, DeepCopy(nil))
+}
+
+func TestCopy_Cyclic(t *testing.T) {
+	type node struct {
+		Next *node
+	}
+	x := &node{}
+	x.Next = &node{Next: x}
+
+	cp := DeepCopy(x).(*node)
+	assert.False(t, cp == x)
+	assert.False(t, cp.Next == x.Next)
+	assert.True(t, cp.Next.Next == cp)
+}
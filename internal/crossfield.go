@@ -0,0 +1,186 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// fieldComparisons maps the directives that compare a field to a sibling field to their operator.
+var fieldComparisons = map[string]string{
+	"eqfield":  "==",
+	"nefield":  "!=",
+	"gtfield":  ">",
+	"gtefield": ">=",
+	"ltfield":  "<",
+	"ltefield": "<=",
+}
+
+// isFieldComparison returns true if the directive compares the field to a sibling field,
+// e.g. "val>=Field(Start)" or "gtfield=Start".
+func isFieldComparison(d directive) bool {
+	if d.name == "val" {
+		return strings.HasPrefix(d.arg, "Field(") && strings.HasSuffix(d.arg, ")")
+	}
+	_, ok := fieldComparisons[d.name]
+	return ok
+}
+
+// comparisonClass returns the class of values that can be compared with one another, or an empty string if values of the type cannot be compared.
+// Pointers are compared by their targets.
+func comparisonClass(refType reflect.Type) string {
+	for refType.Kind() == reflect.Pointer {
+		refType = refType.Elem()
+	}
+	switch {
+	case refType == timeType:
+		return "time"
+	case refType == durationType:
+		return "duration"
+	}
+	switch refType.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Bool:
+		return "bool"
+	}
+	return ""
+}
+
+// compileFieldComparison compiles a directive that compares the field to a sibling field.
+// The types of the two fields must be comparable with one another.
+func compileFieldComparison(refType reflect.Type, fldType reflect.Type, d directive) (condition, error) {
+	operator := d.op
+	name := d.arg
+	if d.name == "val" {
+		// Example: val>=Field(Start)
+		name = strings.TrimSuffix(strings.TrimPrefix(d.arg, "Field("), ")")
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			return condition{}, compileError(d, fmt.Errorf("unsupported operator '%s'", operator))
+		}
+	} else {
+		// Example: gtfield=Start
+		switch operator {
+		case "", "=":
+		default:
+			return condition{}, compileError(d, fmt.Errorf("unsupported operator '%s'", operator))
+		}
+		operator = fieldComparisons[d.name]
+	}
+	sibling, ok := refType.FieldByName(name)
+	if !ok {
+		return condition{}, compileError(d, fmt.Errorf("field '%s' not found in '%v'", name, refType))
+	}
+	class := comparisonClass(fldType)
+	if class == "" || (class == "bool" && operator != "==" && operator != "!=") {
+		return condition{}, compileError(d, fmt.Errorf("directive '%s' is not applicable to '%v'", d.raw, fldType))
+	}
+	if comparisonClass(sibling.Type) != class {
+		return condition{}, compileError(d, fmt.Errorf("field '%s' of type '%v' cannot be compared to '%v'", name, sibling.Type, fldType))
+	}
+	index := sibling.Index
+	return condition{
+		directive: d,
+		check: func(refVal reflect.Value, structVal reflect.Value) error {
+			sibling, err := structVal.FieldByIndexErr(index)
+			if err != nil {
+				// Fields promoted through nil embedded pointers are not compared, the same as nil pointers
+				return nil
+			}
+			a := indirect(refVal)
+			b := indirect(sibling)
+			if a.Kind() == reflect.Pointer || b.Kind() == reflect.Pointer {
+				// Nil pointers are not compared
+				return nil
+			}
+			c := compareValues(class, a, b)
+			switch {
+			case operator == "<=" && c > 0:
+				return fmt.Errorf("must be less than or equal to %s", name)
+			case operator == "<" && c >= 0:
+				return fmt.Errorf("must be less than %s", name)
+			case operator == ">=" && c < 0:
+				return fmt.Errorf("must be greater than or equal to %s", name)
+			case operator == ">" && c <= 0:
+				return fmt.Errorf("must be greater than %s", name)
+			case operator == "!=" && c == 0:
+				return fmt.Errorf("must not equal %s", name)
+			case operator == "==" && c != 0:
+				return fmt.Errorf("must equal %s", name)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// compareValues returns -1, 0 or 1 if the first value is less than, equal to or greater than the second value.
+// Booleans are either equal, or not in which case 1 is returned.
+func compareValues(class string, a reflect.Value, b reflect.Value) int {
+	switch class {
+	case "string":
+		return strings.Compare(a.String(), b.String())
+	case "int", "duration":
+		return compareOrdered(a.Int(), b.Int())
+	case "uint":
+		return compareOrdered(a.Uint(), b.Uint())
+	case "float":
+		return compareOrdered(a.Float(), b.Float())
+	case "time":
+		if !a.CanInterface() || !b.CanInterface() {
+			return 0
+		}
+		return compareTimes(a.Interface().(time.Time), b.Interface().(time.Time))
+	case "bool":
+		if a.Bool() == b.Bool() {
+			return 0
+		}
+		return 1
+	}
+	return 0
+}
+
+// compareTimes returns -1, 0 or 1 if the first time is earlier than, equal to or later than the second time.
+func compareTimes(a time.Time, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// compareOrdered returns -1, 0 or 1 if the first value is less than, equal to or greater than the second value.
+func compareOrdered[T int64 | uint64 | float64](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossField_Compare(t *testing.T) {
+	x := struct {
+		Start    time.Time
+		End      time.Time `dv8:"val>Field(Start)"`
+		Min      int8
+		Max      int64 `dv8:"gtefield=Min"`
+		Low      *float64
+		High     float64 `dv8:"val>=Field(Low)"`
+		Timeout  time.Duration
+		Grace    time.Duration `dv8:"ltfield=Timeout"`
+		Password string        `dv8:"notrim"`
+		Confirm  string        `dv8:"notrim,eqfield=Password"`
+		Old      uint
+		New      uint `dv8:"nefield=Old"`
+		On       bool
+		Off      bool `dv8:"val!=Field(On)"`
+	}{
+		Start:    mustParseTime("2024-01-02"),
+		End:      mustParseTime("2024-01-01"),
+		Min:      5,
+		Max:      3,
+		High:     1,
+		Timeout:  time.Second,
+		Grace:    time.Minute,
+		Password: "secret ",
+		Confirm:  "secret",
+		Old:      1,
+		New:      1,
+		On:       true,
+		Off:      true,
+	}
+	err := ValidateAll(context.Background(), &x)
+	if assert.Error(t, err) {
+		errs := err.(Errors)
+		assert.Len(t, errs, 6)
+		assert.ErrorContains(t, errs[0], "End: must be greater than Start")
+		assert.ErrorContains(t, errs[1], "Max: must be greater than or equal to Min")
+		assert.ErrorContains(t, errs[2], "Grace: must be less than Timeout")
+		assert.ErrorContains(t, errs[3], "Confirm: must equal Password")
+		assert.ErrorContains(t, errs[4], "New: must not equal Old")
+		assert.ErrorContains(t, errs[5], "Off: must not equal On")
+
+		var fe *FieldError
+		if assert.True(t, errors.As(errs[0], &fe)) {
+			assert.Equal(t, "val", fe.Directive)
+			assert.Equal(t, ">", fe.Operator)
+			assert.Equal(t, "Field(Start)", fe.Param)
+		}
+	}
+
+	x.End = mustParseTime("2024-01-03")
+	x.Max = 5
+	x.Grace = time.Millisecond
+	x.Confirm = "secret "
+	x.New = 2
+	x.Off = false
+	low := 2.0
+	x.Low = &low
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "High: must be greater than or equal to Low")
+
+	x.High = 2
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
+
+func TestCrossField_Types(t *testing.T) {
+	x := struct {
+		S string
+		I int
+		U uint
+		B bool
+		T time.Time
+		A string  `dv8:"val>Field(I)"`
+		C int     `dv8:"gtfield=U"`
+		D bool    `dv8:"gtfield=B"`
+		E []int   `dv8:"eqfield=I"`
+		F float64 `dv8:"ltfield=Nope"`
+		G int     `dv8:"eqfield<I"`
+		H *int    `dv8:"eqfield=I"`
+	}{}
+	err := Check(reflect.TypeOf(x))
+	if assert.Error(t, err) {
+		errs := err.(Errors)
+		assert.Len(t, errs, 6)
+		assert.ErrorContains(t, errs[0], "field 'I' of type 'int' cannot be compared to 'string'")
+		assert.ErrorContains(t, errs[1], "field 'U' of type 'uint' cannot be compared to 'int'")
+		assert.ErrorContains(t, errs[2], "directive 'gtfield=B' is not applicable to 'bool'")
+		assert.ErrorContains(t, errs[3], "directive 'eqfield=I' is not applicable to '[]int'")
+		assert.ErrorContains(t, errs[4], "field 'Nope' not found")
+		assert.ErrorContains(t, errs[5], "unsupported operator")
+	}
+}
+
+type crossFieldEmbedded struct {
+	Zip string
+	Min int
+}
+
+func TestCrossField_NilEmbedded(t *testing.T) {
+	x := struct {
+		*crossFieldEmbedded
+		Confirm string `dv8:"eqfield=Zip"`
+		Max     int    `dv8:"val>=Field(Min)"`
+	}{
+		Confirm: "12345",
+		Max:     -1,
+	}
+	err := ValidateAll(context.Background(), &x)
+	assert.NoError(t, err)
+
+	x.crossFieldEmbedded = &crossFieldEmbedded{Zip: "54321", Min: 0}
+	err = ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 2) {
+		assert.ErrorContains(t, err, "Confirm: must equal Zip")
+		assert.ErrorContains(t, err, "Max: must be greater than or equal to Min")
+	}
+}
+
+func TestCrossField_Optional(t *testing.T) {
+	x := struct {
+		Start    time.Time
+		End      time.Time `dv8:"optional,gtfield=Start"`
+		Min      int
+		Max      int `dv8:"optional,val>=Field(Min)"`
+		Password string
+		Confirm  string `dv8:"optional,eqfield=Password,required_with=Password"`
+		Cap      float64
+		Limit    *float64 `dv8:"optional,ltefield=Cap"`
+	}{
+		Start: mustParseTime("2024-01-01"),
+		Min:   5,
+		Cap:   5,
+	}
+	// Zero optional values are not compared
+	err := ValidateAll(context.Background(), &x)
+	assert.NoError(t, err)
+
+	var zero float64
+	x.Limit = &zero
+	err = ValidateAll(context.Background(), &x)
+	assert.NoError(t, err)
+
+	// Non-zero optional values are compared
+	x.End = mustParseTime("2023-01-01")
+	x.Max = 1
+	x.Password = "secret"
+	x.Confirm = "secreT"
+	limit := 10.0
+	x.Limit = &limit
+	err = ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 4) {
+		assert.ErrorContains(t, err, "End: must be greater than Start")
+		assert.ErrorContains(t, err, "Max: must be greater than or equal to Min")
+		assert.ErrorContains(t, err, "Confirm: must equal Password")
+		assert.ErrorContains(t, err, "Limit: must be less than or equal to Cap")
+	}
+
+	// Conditional requirements still apply to zero optional values
+	x.End = time.Time{}
+	x.Max = 0
+	x.Confirm = ""
+	x.Limit = nil
+	err = ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 1) {
+		assert.ErrorContains(t, err, "Confirm: value is required when Password is present")
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// DirectiveFunc validates a value against a custom directive.
+// The value is settable if the data was passed by reference, in which case the function may normalize it.
+// The argument is that of the directive, after removing quotes and escapes.
+type DirectiveFunc func(ctx context.Context, refVal reflect.Value, arg string) error
+
+var (
+	customDirectives    = map[string]DirectiveFunc{}
+	customDirectivesMux sync.RWMutex
+)
+
+// RegisterDirective registers a custom directive.
+// Registering a directive that is already registered replaces it.
+func RegisterDirective(name string, fn DirectiveFunc) error {
+	if name == "" {
+		return fmt.Errorf("invalid directive name '%s'", name)
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return fmt.Errorf("invalid directive name '%s'", name)
+		}
+	}
+	if builtinDirectives[name] {
+		return fmt.Errorf("directive '%s' is built in", name)
+	}
+	if isAlias(name) {
+		return fmt.Errorf("directive '%s' conflicts with an alias", name)
+	}
+	if fn == nil {
+		return fmt.Errorf("missing function of directive '%s'", name)
+	}
+	customDirectivesMux.Lock()
+	customDirectives[name] = fn
+	customDirectivesMux.Unlock()
+	resetPlans()
+	return nil
+}
+
+// customDirective returns the function of the custom directive with the given name, or nil if it is not registered.
+func customDirective(name string) DirectiveFunc {
+	customDirectivesMux.RLock()
+	defer customDirectivesMux.RUnlock()
+	return customDirectives[name]
+}
+
+// compileCustom compiles the custom directives that apply to the value itself.
+func compileCustom(p *plan, dirs []directive) error {
+	var errs Errors
+	for _, d := range dirs {
+		fn := customDirective(d.name)
+		if fn == nil {
+			continue
+		}
+		switch d.op {
+		case "", "=":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", d.op)))
+			continue
+		}
+		p.custom = append(p.custom, custom{
+			directive: d,
+			fn:        fn,
+		})
+	}
+	return errs.join()
+}
+
+// validateCustom validates the value against the custom directives of its plan, in order of appearance.
+// In dry run mode, the functions receive a value that cannot be set.
+func validateCustom(w *walk, p *plan, refVal reflect.Value) error {
+	if len(p.custom) > 0 && w.opts.DryRun {
+		refVal = readOnly(refVal)
+	}
+	for _, c := range p.custom {
+		w.record(refVal)
+		err := c.fn(w.ctx, refVal, c.arg)
+		if err != nil {
+			return &FieldError{
+				Directive: c.name,
+				Operator:  c.op,
+				Param:     c.arg,
+				Value:     valueOf(refVal),
+				Err:       err,
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirective_Register(t *testing.T) {
+	err := RegisterDirective("sku", func(ctx context.Context, refVal reflect.Value, arg string) error {
+		s := strings.ToUpper(refVal.String())
+		if refVal.CanSet() {
+			refVal.SetString(s)
+		}
+		if !strings.HasPrefix(s, "SKU-") {
+			return errors.New("invalid SKU")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Error(t, RegisterDirective("len", func(ctx context.Context, refVal reflect.Value, arg string) error { return nil }))
+	assert.Error(t, RegisterDirective("a-b", func(ctx context.Context, refVal reflect.Value, arg string) error { return nil }))
+	assert.Error(t, RegisterDirective("nofunc", nil))
+
+	type product struct {
+		SKU  string   `dv8:"required,sku,len==8"`
+		SKUs []string `dv8:"arrlen>0,sku"`
+	}
+	p := &product{SKU: " sku-1234 ", SKUs: []string{"sku-1", "SKU-2"}}
+	err = Validate(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "SKU-1234", p.SKU)
+	assert.Equal(t, []string{"SKU-1", "SKU-2"}, p.SKUs)
+
+	p = &product{SKU: "abcd1234", SKUs: []string{"sku-1"}}
+	err = Validate(p)
+	var fe *FieldError
+	if assert.ErrorAs(t, err, &fe) {
+		assert.Equal(t, "sku", fe.Directive)
+		assert.Equal(t, "SKU: invalid SKU", fe.Error())
+	}
+	p = &product{SKU: "sku-1234", SKUs: []string{"sku-1", "x"}}
+	err = Validate(p)
+	assert.ErrorContains(t, err, "SKUs: [1]: invalid SKU")
+}
+
+func TestDirective_AnyKind(t *testing.T) {
+	var args []string
+	err := RegisterDirective("tenant", func(ctx context.Context, refVal reflect.Value, arg string) error {
+		args = append(args, arg)
+		if ctx.Value(ctxValueKey) != arg {
+			return errors.New("wrong tenant")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	type account struct {
+		ID string
+	}
+	type data struct {
+		Account account `dv8:"tenant=acme"`
+		Count   int     `dv8:"val>0,tenant 'acme'"`
+		Any     any     `dv8:"tenant=acme"`
+	}
+	ctx := context.WithValue(context.Background(), ctxValueKey, "acme")
+	d := &data{Count: 1, Any: "x"}
+	err = ValidateContext(ctx, d)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"acme", "acme", "acme"}, args)
+
+	err = ValidateContext(context.Background(), d)
+	assert.ErrorContains(t, err, "Account: wrong tenant")
+
+	// Strict mode recognizes custom directives on any kind
+	err = ValidateOptions(ctx, d, Options{Strict: true})
+	assert.NoError(t, err)
+
+	// Only the = operator is supported
+	type bad struct {
+		Name string `dv8:"tenant>=acme"`
+	}
+	err = Validate(&bad{})
+	assert.ErrorContains(t, err, "unsupported operator '>='")
+}
+
+func TestDirective_ResetsPlans(t *testing.T) {
+	type data struct {
+		Name string `dv8:"later"`
+	}
+	err := Validate(&data{})
+	assert.NoError(t, err)
+
+	err = RegisterDirective("later", func(ctx context.Context, refVal reflect.Value, arg string) error {
+		return errors.New("later")
+	})
+	assert.NoError(t, err)
+	err = Validate(&data{})
+	assert.ErrorContains(t, err, "Name: later")
+}
@@ -16,77 +16,88 @@ limitations under the License.
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
-	"strings"
 	"time"
 )
 
-// validateDuration validates the value of a duration against the tags.
-func validateDuration(refVal reflect.Value, tags []string) (err error) {
-	d := time.Duration(refVal.Int())
+// durationDirectives are the directives applicable to a duration.
+var durationDirectives = []string{"required", "optional", "default", "val"}
+
+// compileDuration compiles the directives that apply to a duration.
+func compileDuration(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if d == 0 && strings.HasPrefix(t, "default=") {
-			def, err := time.ParseDuration(t[len("default="):])
+		case "default":
+			def, err := time.ParseDuration(d.arg)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if def != d {
-				d = def
-				changed = true
+			if def == 0 {
+				continue
 			}
+			defVal := reflect.ValueOf(def)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.Int() != 0 {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetInt(int64(d))
-	}
-	if d == 0 && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("non-zero value is required", reflect.Value.IsZero))
 	}
 	// Range constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<2s
-			operator := t[3:4]
-			var v time.Duration
-			if t[4] == '=' {
-				operator += "="
-				v, err = time.ParseDuration(t[5:])
-			} else {
-				v, err = time.ParseDuration(t[4:])
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "<=" && d > v:
-				err = fmt.Errorf("must be less than or equal to %v", v)
-			case operator == "<" && d >= v:
-				err = fmt.Errorf("must be less than %v", v)
-			case operator == ">=" && d < v:
-				err = fmt.Errorf("must be greater than or equal to %v", v)
-			case operator == ">" && d <= v:
-				err = fmt.Errorf("must be greater than %v", v)
-			case operator == "!=" && d == v:
-				err = fmt.Errorf("must not equal %v", v)
-			case operator == "==" && d != v:
-				err = fmt.Errorf("must equal %v", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val<2s
+		operator := d.op
+		v, err := time.ParseDuration(d.arg)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				d := time.Duration(refVal.Int())
+				switch {
+				case operator == "<=" && d > v:
+					return fmt.Errorf("must be less than or equal to %v", v)
+				case operator == "<" && d >= v:
+					return fmt.Errorf("must be less than %v", v)
+				case operator == ">=" && d < v:
+					return fmt.Errorf("must be greater than or equal to %v", v)
+				case operator == ">" && d <= v:
+					return fmt.Errorf("must be greater than %v", v)
+				case operator == "!=" && d == v:
+					return fmt.Errorf("must not equal %v", v)
+				case operator == "==" && d != v:
+					return fmt.Errorf("must equal %v", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
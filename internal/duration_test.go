@@ -142,3 +142,15 @@ func TestDuration_Val(t *testing.T) {
 	err = Validate(&bad)
 	assert.ErrorContains(t, err, "operator")
 }
+
+func TestDuration_Optional(t *testing.T) {
+	x := struct {
+		D time.Duration `dv8:"optional,val>=1s"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.D = time.Millisecond
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "must be greater than or equal to 1s")
+}
@@ -0,0 +1,295 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// PathStyle determines how a path is rendered.
+type PathStyle int
+
+const (
+	PathStyleDefault     PathStyle = iota // Names: [2]
+	PathStyleJSONPointer                  // /names/2
+	PathStyleDotted                       // names[2]
+)
+
+// PathSegment is a single step in the path to a field.
+type PathSegment struct {
+	Field    string            // Name of the struct field, or empty if the segment is an item of an array or map
+	Tag      reflect.StructTag // Tag of the struct field
+	Index    any               // Index of the item in the array or key of the item in the map
+	Embedded bool              // The struct field is an embedded struct, or a pointer to one
+}
+
+// String returns the segment in the form "Name" or "[2]".
+func (s PathSegment) String() string {
+	if s.Field != "" {
+		return s.Field
+	}
+	return fmt.Sprintf("[%v]", s.Index)
+}
+
+// Name returns the name of the field as it appears in the tag with the given key, e.g. "json", "yaml" or "form".
+// The Go name of the field is returned if the tag key is empty, or if the tag does not rename the field or excludes it with "-".
+func (s PathSegment) Name(tagKey string) string {
+	if s.Field == "" || tagKey == "" {
+		return s.Field
+	}
+	name, _, _ := strings.Cut(s.Tag.Get(tagKey), ",")
+	if name == "" || name == "-" {
+		return s.Field
+	}
+	return name
+}
+
+// Flattened returns true if the field is embedded and not named in the tag with the given key,
+// in which case its fields are rendered as if they were fields of the parent struct, as done by encoding/json.
+func (s PathSegment) Flattened(tagKey string) bool {
+	if !s.Embedded || tagKey == "" {
+		return false
+	}
+	name, _, _ := strings.Cut(s.Tag.Get(tagKey), ",")
+	return name == ""
+}
+
+// Path is the path to a field from the validated value.
+type Path []PathSegment
+
+// String returns the path in the default style, e.g. "Names: [2]".
+func (p Path) String() string {
+	return p.Format(PathStyleDefault, "")
+}
+
+/*
+Format renders the path in the given style.
+If a tag key such as "json", "yaml" or "form" is provided, field names are taken from the corresponding tag,
+and embedded structs that are not named in the tag are omitted, as their fields are flattened into the parent.
+
+Example:
+
+	p.Format(PathStyleDefault, "")        // Names: [2]
+	p.Format(PathStyleJSONPointer, "json") // /names/2
+	p.Format(PathStyleDotted, "json")      // names[2]
+*/
+func (p Path) Format(style PathStyle, tagKey string) string {
+	var b strings.Builder
+	i := 0
+	for _, seg := range p {
+		if seg.Flattened(tagKey) {
+			continue
+		}
+		switch style {
+		case PathStyleJSONPointer:
+			b.WriteString("/")
+			if seg.Field != "" {
+				b.WriteString(escapeJSONPointer(seg.Name(tagKey)))
+			} else {
+				b.WriteString(escapeJSONPointer(fmt.Sprintf("%v", seg.Index)))
+			}
+		case PathStyleDotted:
+			if seg.Field != "" {
+				if i > 0 {
+					b.WriteString(".")
+				}
+				b.WriteString(seg.Name(tagKey))
+			} else {
+				fmt.Fprintf(&b, "[%v]", seg.Index)
+			}
+		default:
+			if i > 0 {
+				b.WriteString(": ")
+			}
+			if seg.Field != "" {
+				b.WriteString(seg.Name(tagKey))
+			} else {
+				fmt.Fprintf(&b, "[%v]", seg.Index)
+			}
+		}
+		i++
+	}
+	return b.String()
+}
+
+// escapeJSONPointer escapes a reference token of a JSON Pointer as per RFC 6901.
+func escapeJSONPointer(token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return token
+}
+
+/*
+FieldError is a validation failure of a single field.
+
+Example:
+
+	FieldError{
+		Path:      Path{{Field: "Names"}, {Index: 2}},
+		Directive: "len",
+		Operator:  "<=",
+		Param:     "32",
+		Value:     "Supercalifragilisticexpialidocious",
+		Err:       errors.New("length must be less than or equal to 32"),
+	}
+*/
+type FieldError struct {
+	Path      Path   // Path to the field from the validated value
+	Directive string // Directive that failed, e.g. "required", "len", "val", "regexp", "oneof". Empty for errors returned by a Validator
+	Operator  string // Operator of the directive, if applicable, e.g. "<="
+	Param     string // Parameter of the directive, if applicable, e.g. "32"
+	Value     any    // Offending value
+	Err       error  // Underlying error
+
+	style  PathStyle // Style in which the path is rendered by Error
+	tagKey string    // Tag key of the names of fields in the path rendered by Error
+}
+
+// Error returns the error prefixed by the path to the field, e.g. "Names: [2]: length must be greater than 0".
+func (e *FieldError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Err.Error()
+	}
+	return e.Path.Format(e.style, e.tagKey) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// atPath prepends the path segment to the path of the error.
+// Errors that are not yet a FieldError are wrapped in one.
+func atPath(seg PathSegment, err error) error {
+	switch e := err.(type) {
+	case Errors:
+		errs := make(Errors, len(e))
+		for i := range e {
+			errs[i] = atPath(seg, e[i])
+		}
+		return errs
+	case *FieldError:
+		fe := *e
+		fe.Path = append(Path{seg}, e.Path...)
+		return &fe
+	default:
+		return &FieldError{
+			Path: Path{seg},
+			Err:  err,
+		}
+	}
+}
+
+// withPathStyle sets the style in which the paths of the errors are rendered.
+func withPathStyle(err error, style PathStyle, tagKey string) error {
+	switch e := err.(type) {
+	case Errors:
+		errs := make(Errors, len(e))
+		for i := range e {
+			errs[i] = withPathStyle(e[i], style, tagKey)
+		}
+		return errs
+	case *FieldError:
+		fe := *e
+		fe.style = style
+		fe.tagKey = tagKey
+		return &fe
+	default:
+		return err
+	}
+}
+
+// TagError is an error in the dv8 tag of a field of a struct, such as an unknown directive.
+type TagError struct {
+	Type  reflect.Type // Type of the struct
+	Field string       // Name of the field
+	Err   error        // Underlying error
+}
+
+// Error returns the error along with the name of the field and the type of the struct.
+func (e *TagError) Error() string {
+	return fmt.Sprintf("invalid tag of field '%s' of '%v': %v", e.Field, e.Type, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TagError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is a list of validation errors collected when validating all fields rather than stopping at the first failure.
+type Errors []error
+
+// Error returns the errors, one per line.
+func (e Errors) Error() string {
+	var b strings.Builder
+	for i, err := range e {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the list of errors.
+func (e Errors) Unwrap() []error {
+	return e
+}
+
+// collect appends an error to the list, flattening nested lists.
+func (e Errors) collect(err error) Errors {
+	if nested, ok := err.(Errors); ok {
+		return append(e, nested...)
+	}
+	return append(e, err)
+}
+
+// appendUnique appends an error to the list, flattening nested lists and skipping errors already in the list.
+func appendUnique(e Errors, err error) Errors {
+	if err == nil {
+		return e
+	}
+	if nested, ok := err.(Errors); ok {
+		for _, n := range nested {
+			e = appendUnique(e, n)
+		}
+		return e
+	}
+	for _, x := range e {
+		if x == err {
+			return e
+		}
+	}
+	return append(e, err)
+}
+
+// join returns nil if the list is empty, the error if the list holds only one, or the list otherwise.
+func (e Errors) join() error {
+	if len(e) == 1 {
+		return e[0]
+	}
+	return e.orNil()
+}
+
+// orNil returns nil if the list is empty, or the list otherwise.
+func (e Errors) orNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
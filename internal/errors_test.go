@@ -0,0 +1,126 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors_PathFormat(t *testing.T) {
+	type item struct {
+		Qty int `json:"qty" yaml:"quantity" dv8:"val>0"`
+	}
+	type order struct {
+		Items  []item           `json:"items,omitempty" form:"-"`
+		Notes  map[string]item  `json:"notes/~"`
+		Hidden string           `json:"-" dv8:"required"`
+		Plain  map[int][]string `dv8:"len>0"`
+	}
+
+	x := order{
+		Items: []item{{Qty: 1}, {Qty: 0}},
+	}
+	err := Validate(&x)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Items: [1]: Qty", fe.Path.String())
+		assert.Equal(t, "Items: [1]: Qty", fe.Path.Format(PathStyleDefault, ""))
+		assert.Equal(t, "/Items/1/Qty", fe.Path.Format(PathStyleJSONPointer, ""))
+		assert.Equal(t, "/items/1/qty", fe.Path.Format(PathStyleJSONPointer, "json"))
+		assert.Equal(t, "/Items/1/quantity", fe.Path.Format(PathStyleJSONPointer, "yaml"))
+		assert.Equal(t, "Items[1].Qty", fe.Path.Format(PathStyleDotted, "form"))
+		assert.Equal(t, "items[1].qty", fe.Path.Format(PathStyleDotted, "json"))
+		assert.Equal(t, "items: [1]: qty", fe.Path.Format(PathStyleDefault, "json"))
+	}
+
+	x.Items = nil
+	x.Notes = map[string]item{"a/b": {Qty: 0}}
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "/notes~1~0/a~1b/qty", fe.Path.Format(PathStyleJSONPointer, "json"))
+		assert.Equal(t, "notes/~[a/b].qty", fe.Path.Format(PathStyleDotted, "json"))
+	}
+
+	x.Notes = nil
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "/Hidden", fe.Path.Format(PathStyleJSONPointer, "json"))
+	}
+
+	x.Hidden = "x"
+	x.Plain = map[int][]string{5: {"a", ""}}
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "/Plain/5/1", fe.Path.Format(PathStyleJSONPointer, "json"))
+		assert.Equal(t, "Plain[5][1]", fe.Path.Format(PathStyleDotted, "json"))
+	}
+}
+
+func TestErrors_PathStyleOption(t *testing.T) {
+	type item struct {
+		Qty int `json:"qty" dv8:"val>0"`
+	}
+	type order struct {
+		Items []item `json:"items"`
+		Name  string `json:"name" dv8:"required"`
+	}
+	x := order{Items: []item{{Qty: 1}, {Qty: 0}}}
+
+	err := ValidateOptions(context.Background(), &x, Options{})
+	assert.EqualError(t, err, "Items: [1]: Qty: must be greater than 0")
+	err = ValidateOptions(context.Background(), &x, Options{PathStyle: PathStyleJSONPointer, PathTagKey: "json"})
+	assert.EqualError(t, err, "/items/1/qty: must be greater than 0")
+	err = ValidateOptions(context.Background(), &x, Options{All: true, PathStyle: PathStyleDotted, PathTagKey: "json"})
+	assert.EqualError(t, err, "items[1].qty: must be greater than 0\nname: value is required")
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Items: [1]: Qty", fe.Path.String())
+	}
+}
+
+type ErrorsBase struct {
+	ID int `json:"id" dv8:"required"`
+}
+
+func TestErrors_PathEmbedded(t *testing.T) {
+	type named struct {
+		Code string `json:"code" dv8:"required"`
+	}
+	type doc struct {
+		ErrorsBase
+		*named `json:"named"`
+		Name   string `json:"name"`
+	}
+	x := doc{named: &named{Code: "x"}}
+	err := Validate(&x)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "ErrorsBase: ID", fe.Path.String())
+		assert.Equal(t, "/ErrorsBase/ID", fe.Path.Format(PathStyleJSONPointer, ""))
+		assert.Equal(t, "/id", fe.Path.Format(PathStyleJSONPointer, "json"))
+		assert.Equal(t, "id", fe.Path.Format(PathStyleDotted, "json"))
+	}
+
+	// Embedded structs named in the tag are not flattened
+	x.ID = 1
+	x.named.Code = ""
+	err = ValidateOptions(context.Background(), &x, Options{PathStyle: PathStyleJSONPointer, PathTagKey: "json"})
+	assert.EqualError(t, err, "/named/code: value is required")
+}
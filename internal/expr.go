@@ -0,0 +1,548 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exprType is the type of a value in an expression.
+type exprType int
+
+const (
+	exprNumber     exprType = iota + 1 // float64
+	exprString                         // string
+	exprBool                           // bool
+	exprTime                           // time.Time
+	exprCollection                     // Array, slice or map, whose only use is in len()
+)
+
+// String returns the name of the type, as it appears in error messages.
+func (t exprType) String() string {
+	switch t {
+	case exprNumber:
+		return "number"
+	case exprString:
+		return "string"
+	case exprBool:
+		return "bool"
+	case exprTime:
+		return "time"
+	case exprCollection:
+		return "collection"
+	}
+	return "unknown"
+}
+
+// exprFunc evaluates a compiled expression, or a part of it, against the value of a struct.
+// Values are of type float64, string, bool, time.Time, or reflect.Value for collections.
+type exprFunc func(structVal reflect.Value) (any, error)
+
+// expression is a compiled struct-level expr directive, e.g. "expr Discount <= Subtotal * 0.5".
+type expression struct {
+	directive
+	eval exprFunc
+}
+
+/*
+compileExpr compiles an expression that references the fields of the struct.
+Expressions support numbers, strings in single or double quotes, true and false,
+field names (including nested fields separated by a dot), the arithmetic operators + - * / %,
+the comparison operators == != < <= > >=, the boolean operators && || !, parentheses, and len().
+Numbers are evaluated as float64. The expression must evaluate to a bool.
+
+Example:
+
+	Discount <= Subtotal * 0.5
+	len(Items) == Count && (Note == '' || len(Note) <= 100)
+*/
+func compileExpr(refType reflect.Type, d directive) (expression, error) {
+	tokens, err := lexExpr(d.arg)
+	if err != nil {
+		return expression{}, compileError(d, err)
+	}
+	ep := exprParser{refType: refType, tokens: tokens}
+	eval, typ, err := ep.parseOr()
+	if err == nil && ep.peek() != "" {
+		err = fmt.Errorf("unexpected '%s'", ep.peek())
+	}
+	if err == nil && typ != exprBool {
+		err = fmt.Errorf("expression must be a bool but is a %v", typ)
+	}
+	if err != nil {
+		return expression{}, compileError(d, err)
+	}
+	return expression{directive: d, eval: eval}, nil
+}
+
+// validateExprs validates the struct against its expressions.
+func validateExprs(p *plan, refVal reflect.Value) error {
+	for _, x := range p.exprs {
+		result, err := x.eval(refVal)
+		if err == nil && !result.(bool) {
+			err = fmt.Errorf("must satisfy '%s'", x.arg)
+		}
+		if err != nil {
+			return &FieldError{
+				Directive: x.name,
+				Param:     x.arg,
+				Value:     valueOf(refVal),
+				Err:       err,
+			}
+		}
+	}
+	return nil
+}
+
+// lexExpr breaks an expression into its tokens.
+// Strings retain their quotes so that they are distinguishable from field names.
+func lexExpr(s string) (tokens []string, err error) {
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c >= '0' && c <= '9' || c == '.' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9':
+			start := i
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.' || s[i] == 'e' || s[i] == 'E' ||
+				(s[i] == '-' || s[i] == '+') && (s[i-1] == 'e' || s[i-1] == 'E')) {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		case isNameChar(c):
+			start := i
+			for i < len(s) && (isNameChar(s[i]) || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, s[start:i])
+		case c == '\'' || c == '"':
+			start := i
+			i++
+			for i < len(s) && s[i] != c {
+				if s[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(s) {
+				return nil, fmt.Errorf("unterminated string at column %d", start+1)
+			}
+			i++
+			tokens = append(tokens, s[start:i])
+		case strings.HasPrefix(s[i:], "&&") || strings.HasPrefix(s[i:], "||") ||
+			strings.HasPrefix(s[i:], "==") || strings.HasPrefix(s[i:], "!=") ||
+			strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">="):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case strings.IndexByte("+-*/%<>!()", c) >= 0:
+			tokens = append(tokens, s[i:i+1])
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected '%c' at column %d", c, i+1)
+		}
+	}
+	return tokens, nil
+}
+
+// exprParser parses the tokens of an expression by recursive descent, compiling them as it goes.
+type exprParser struct {
+	refType reflect.Type
+	tokens  []string
+	pos     int
+}
+
+// peek returns the next token, or an empty string at the end of the expression.
+func (ep *exprParser) peek() string {
+	if ep.pos < len(ep.tokens) {
+		return ep.tokens[ep.pos]
+	}
+	return ""
+}
+
+// next consumes and returns the next token.
+func (ep *exprParser) next() string {
+	tok := ep.peek()
+	ep.pos++
+	return tok
+}
+
+// parseOr parses a || b.
+func (ep *exprParser) parseOr() (exprFunc, exprType, error) {
+	left, lt, err := ep.parseAnd()
+	if err != nil {
+		return nil, 0, err
+	}
+	for ep.peek() == "||" {
+		ep.next()
+		right, rt, err := ep.parseAnd()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lt != exprBool || rt != exprBool {
+			return nil, 0, fmt.Errorf("operator '||' is not applicable to %v and %v", lt, rt)
+		}
+		l, r := left, right
+		left = func(structVal reflect.Value) (any, error) {
+			a, err := l(structVal)
+			if err != nil || a.(bool) {
+				return a, err
+			}
+			return r(structVal)
+		}
+	}
+	return left, lt, nil
+}
+
+// parseAnd parses a && b.
+func (ep *exprParser) parseAnd() (exprFunc, exprType, error) {
+	left, lt, err := ep.parseCompare()
+	if err != nil {
+		return nil, 0, err
+	}
+	for ep.peek() == "&&" {
+		ep.next()
+		right, rt, err := ep.parseCompare()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lt != exprBool || rt != exprBool {
+			return nil, 0, fmt.Errorf("operator '&&' is not applicable to %v and %v", lt, rt)
+		}
+		l, r := left, right
+		left = func(structVal reflect.Value) (any, error) {
+			a, err := l(structVal)
+			if err != nil || !a.(bool) {
+				return a, err
+			}
+			return r(structVal)
+		}
+	}
+	return left, lt, nil
+}
+
+// parseCompare parses a == b, a < b, etc.
+func (ep *exprParser) parseCompare() (exprFunc, exprType, error) {
+	left, lt, err := ep.parseAdd()
+	if err != nil {
+		return nil, 0, err
+	}
+	op := ep.peek()
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+	default:
+		return left, lt, nil
+	}
+	ep.next()
+	right, rt, err := ep.parseAdd()
+	if err != nil {
+		return nil, 0, err
+	}
+	if lt != rt || lt == exprCollection || (lt == exprBool && op != "==" && op != "!=") {
+		return nil, 0, fmt.Errorf("operator '%s' is not applicable to %v and %v", op, lt, rt)
+	}
+	return func(structVal reflect.Value) (any, error) {
+		a, err := left(structVal)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(structVal)
+		if err != nil {
+			return nil, err
+		}
+		var c int
+		switch a := a.(type) {
+		case float64:
+			c = compareOrdered(a, b.(float64))
+		case string:
+			c = strings.Compare(a, b.(string))
+		case time.Time:
+			c = compareTimes(a, b.(time.Time))
+		case bool:
+			if a != b.(bool) {
+				c = 1
+			}
+		}
+		switch op {
+		case "==":
+			return c == 0, nil
+		case "!=":
+			return c != 0, nil
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		default:
+			return c >= 0, nil
+		}
+	}, exprBool, nil
+}
+
+// parseAdd parses a + b and a - b.
+func (ep *exprParser) parseAdd() (exprFunc, exprType, error) {
+	left, lt, err := ep.parseMul()
+	if err != nil {
+		return nil, 0, err
+	}
+	for ep.peek() == "+" || ep.peek() == "-" {
+		op := ep.next()
+		right, rt, err := ep.parseMul()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lt != rt || (lt != exprNumber && !(lt == exprString && op == "+")) {
+			return nil, 0, fmt.Errorf("operator '%s' is not applicable to %v and %v", op, lt, rt)
+		}
+		l, r := left, right
+		left = func(structVal reflect.Value) (any, error) {
+			a, err := l(structVal)
+			if err != nil {
+				return nil, err
+			}
+			b, err := r(structVal)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := a.(string); ok {
+				return s + b.(string), nil
+			}
+			if op == "+" {
+				return a.(float64) + b.(float64), nil
+			}
+			return a.(float64) - b.(float64), nil
+		}
+	}
+	return left, lt, nil
+}
+
+// parseMul parses a * b, a / b and a % b.
+func (ep *exprParser) parseMul() (exprFunc, exprType, error) {
+	left, lt, err := ep.parseUnary()
+	if err != nil {
+		return nil, 0, err
+	}
+	for ep.peek() == "*" || ep.peek() == "/" || ep.peek() == "%" {
+		op := ep.next()
+		right, rt, err := ep.parseUnary()
+		if err != nil {
+			return nil, 0, err
+		}
+		if lt != exprNumber || rt != exprNumber {
+			return nil, 0, fmt.Errorf("operator '%s' is not applicable to %v and %v", op, lt, rt)
+		}
+		l, r := left, right
+		left = func(structVal reflect.Value) (any, error) {
+			a, err := l(structVal)
+			if err != nil {
+				return nil, err
+			}
+			b, err := r(structVal)
+			if err != nil {
+				return nil, err
+			}
+			x, y := a.(float64), b.(float64)
+			switch op {
+			case "*":
+				return x * y, nil
+			case "/":
+				if y == 0 {
+					return nil, errors.New("division by zero")
+				}
+				return x / y, nil
+			default:
+				if y == 0 {
+					return nil, errors.New("division by zero")
+				}
+				return math.Mod(x, y), nil
+			}
+		}
+	}
+	return left, lt, nil
+}
+
+// parseUnary parses !a and -a.
+func (ep *exprParser) parseUnary() (exprFunc, exprType, error) {
+	op := ep.peek()
+	if op != "!" && op != "-" {
+		return ep.parsePrimary()
+	}
+	ep.next()
+	operand, t, err := ep.parseUnary()
+	if err != nil {
+		return nil, 0, err
+	}
+	if (op == "!" && t != exprBool) || (op == "-" && t != exprNumber) {
+		return nil, 0, fmt.Errorf("operator '%s' is not applicable to %v", op, t)
+	}
+	return func(structVal reflect.Value) (any, error) {
+		a, err := operand(structVal)
+		if err != nil {
+			return nil, err
+		}
+		if op == "!" {
+			return !a.(bool), nil
+		}
+		return -a.(float64), nil
+	}, t, nil
+}
+
+// parsePrimary parses literals, field names, len() and parenthesized expressions.
+func (ep *exprParser) parsePrimary() (exprFunc, exprType, error) {
+	tok := ep.next()
+	switch {
+	case tok == "":
+		return nil, 0, errors.New("unexpected end of expression")
+	case tok == "(":
+		inner, t, err := ep.parseOr()
+		if err != nil {
+			return nil, 0, err
+		}
+		if ep.next() != ")" {
+			return nil, 0, errors.New("expected ')'")
+		}
+		return inner, t, nil
+	case tok == "true" || tok == "false":
+		b := tok == "true"
+		return func(reflect.Value) (any, error) { return b, nil }, exprBool, nil
+	case tok[0] == '\'' || tok[0] == '"':
+		s := strings.ReplaceAll(tok[1:len(tok)-1], "\\"+tok[:1], tok[:1])
+		return func(reflect.Value) (any, error) { return s, nil }, exprString, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		f, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid number '%s'", tok)
+		}
+		return func(reflect.Value) (any, error) { return f, nil }, exprNumber, nil
+	case tok == "len" && ep.peek() == "(":
+		ep.next()
+		inner, t, err := ep.parseOr()
+		if err != nil {
+			return nil, 0, err
+		}
+		if ep.next() != ")" {
+			return nil, 0, errors.New("expected ')'")
+		}
+		if t != exprString && t != exprCollection {
+			return nil, 0, fmt.Errorf("len is not applicable to %v", t)
+		}
+		return func(structVal reflect.Value) (any, error) {
+			v, err := inner(structVal)
+			if err != nil {
+				return nil, err
+			}
+			if s, ok := v.(string); ok {
+				return float64(len([]rune(s))), nil
+			}
+			return float64(v.(reflect.Value).Len()), nil
+		}, exprNumber, nil
+	case isNameChar(tok[0]):
+		return ep.compileField(tok)
+	}
+	return nil, 0, fmt.Errorf("unexpected '%s'", tok)
+}
+
+// compileField compiles a reference to a field of the struct, or to a nested field separated by a dot.
+// Nil pointers along the way evaluate to the zero value of the field.
+func (ep *exprParser) compileField(name string) (exprFunc, exprType, error) {
+	refType := ep.refType
+	var indexes [][]int
+	for _, part := range strings.Split(name, ".") {
+		for refType.Kind() == reflect.Pointer {
+			refType = refType.Elem()
+		}
+		if refType.Kind() != reflect.Struct {
+			return nil, 0, fmt.Errorf("field '%s' not found in '%v'", name, ep.refType)
+		}
+		fld, ok := refType.FieldByName(part)
+		if !ok || !fld.IsExported() {
+			return nil, 0, fmt.Errorf("field '%s' not found in '%v'", name, ep.refType)
+		}
+		indexes = append(indexes, fld.Index)
+		refType = fld.Type
+	}
+	for refType.Kind() == reflect.Pointer {
+		refType = refType.Elem()
+	}
+	var t exprType
+	switch refType.Kind() {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		t = exprCollection
+	default:
+		switch comparisonClass(refType) {
+		case "int", "uint", "float", "duration":
+			t = exprNumber
+		case "string":
+			t = exprString
+		case "bool":
+			t = exprBool
+		case "time":
+			t = exprTime
+		default:
+			return nil, 0, fmt.Errorf("field '%s' of type '%v' cannot be used in an expression", name, refType)
+		}
+	}
+	return func(structVal reflect.Value) (any, error) {
+		v := structVal
+		for _, index := range indexes {
+			v = indirect(v)
+			if v.Kind() == reflect.Pointer {
+				v = reflect.Zero(refType)
+				break
+			}
+			// Fields promoted through nil embedded pointers evaluate to zero as well
+			fld, err := v.FieldByIndexErr(index)
+			if err != nil {
+				v = reflect.Zero(refType)
+				break
+			}
+			v = fld
+		}
+		v = indirect(v)
+		if v.Kind() == reflect.Pointer {
+			v = reflect.Zero(refType)
+		}
+		switch t {
+		case exprNumber:
+			switch comparisonClass(refType) {
+			case "uint":
+				return float64(v.Uint()), nil
+			case "float":
+				return v.Float(), nil
+			default:
+				return float64(v.Int()), nil
+			}
+		case exprString:
+			return v.String(), nil
+		case exprBool:
+			return v.Bool(), nil
+		case exprTime:
+			if !v.CanInterface() {
+				return time.Time{}, nil
+			}
+			return v.Interface().(time.Time), nil
+		default:
+			return v, nil
+		}
+	}, t, nil
+}
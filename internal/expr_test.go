@@ -0,0 +1,246 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpr_Marker(t *testing.T) {
+	type order struct {
+		_        struct{} `dv8:"expr Discount <= Subtotal * 0.5,expr len(Items) == Count"`
+		Subtotal float64
+		Discount float64  `dv8:"val>=0"`
+		Items    []string `dv8:"len>0"`
+		Count    int
+	}
+	x := order{
+		Subtotal: 100,
+		Discount: 60,
+		Items:    []string{"a", "b"},
+		Count:    2,
+	}
+	err := Validate(&x)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "must satisfy 'Discount <= Subtotal * 0.5'", fe.Error())
+		assert.Equal(t, "expr", fe.Directive)
+		assert.Equal(t, "Discount <= Subtotal * 0.5", fe.Param)
+	}
+
+	x.Discount = 50
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.Count = 3
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "must satisfy 'len(Items) == Count'")
+
+	// Not evaluated if fields are invalid
+	x.Items = []string{"a", "b", ""}
+	err = ValidateAll(context.Background(), &x)
+	if assert.Error(t, err) {
+		assert.Len(t, err.(Errors), 1)
+		assert.ErrorContains(t, err, "Items: [2]: length")
+	}
+}
+
+func TestExpr_Field(t *testing.T) {
+	type period struct {
+		Start time.Time
+		End   time.Time
+		Label string
+	}
+	type booking struct {
+		Period *period `dv8:"expr End > Start && (Label == '' || len(Label) <= 5)"`
+		Guests int
+	}
+	x := booking{
+		Period: &period{
+			Start: mustParseTime("2024-01-02"),
+			End:   mustParseTime("2024-01-01"),
+		},
+	}
+	err := Validate(&x)
+	assert.ErrorContains(t, err, "Period: must satisfy")
+
+	x.Period.End = mustParseTime("2024-01-03")
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.Period.Label = " Long label "
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Period: must satisfy")
+
+	x.Period.Label = " Short "
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
+
+func TestExpr_Eval(t *testing.T) {
+	type inner struct {
+		N int
+	}
+	type data struct {
+		I   int
+		U   uint8
+		F   float32
+		S   string
+		B   bool
+		D   time.Duration
+		M   map[string]int
+		In  *inner
+		Nil *inner
+	}
+	x := data{
+		I:  7,
+		U:  3,
+		F:  1.5,
+		S:  "héllo",
+		B:  true,
+		D:  time.Second,
+		M:  map[string]int{"a": 1},
+		In: &inner{N: 4},
+	}
+	testCases := []struct {
+		expr string
+		ok   bool
+	}{
+		{"I == 7", true},
+		{"I % U == 1", true},
+		{"-I + 10 == U", true},
+		{"I / 2 == 3.5", true},
+		{"F * 2 == U", true},
+		{"1e3 > I", true},
+		{"S + '!' == \"héllo!\"", true},
+		{"len(S) == 5", true},
+		{"S > 'a' && S < 'z'", true},
+		{"B && !false", true},
+		{"B != true || I > 100", false},
+		{"D == 1000000000", true},
+		{"len(M) == 1", true},
+		{"In.N == 4", true},
+		{"Nil.N == 0", true},
+		{"(I + 1) * 2 == 16", true},
+		{"I + 1 * 2 == 16", false},
+		{"S == 'it\\'s'", false},
+	}
+	for _, tc := range testCases {
+		xp, err := compileExpr(reflect.TypeOf(x), directive{name: "expr", arg: tc.expr})
+		if !assert.NoError(t, err, tc.expr) {
+			continue
+		}
+		result, err := xp.eval(reflect.ValueOf(x))
+		if assert.NoError(t, err, tc.expr) {
+			assert.Equal(t, tc.ok, result, tc.expr)
+		}
+	}
+
+	// Division by zero
+	xp, err := compileExpr(reflect.TypeOf(x), directive{name: "expr", arg: "I / (U - 3) > 0"})
+	if assert.NoError(t, err) {
+		_, err = xp.eval(reflect.ValueOf(x))
+		assert.ErrorContains(t, err, "division by zero")
+	}
+}
+
+func TestExpr_CompileErrors(t *testing.T) {
+	type data struct {
+		I int
+		S string
+		B bool
+		T time.Time
+		C chan int
+	}
+	refType := reflect.TypeOf(data{})
+	testCases := []struct {
+		expr string
+		err  string
+	}{
+		{"I", "must be a bool but is a number"},
+		{"I == S", "operator '==' is not applicable to number and string"},
+		{"S - S == ''", "operator '-' is not applicable to string and string"},
+		{"B < true", "operator '<' is not applicable to bool and bool"},
+		{"I && B", "operator '&&' is not applicable to number and bool"},
+		{"!I", "operator '!' is not applicable to number"},
+		{"len(I) > 0", "len is not applicable to number"},
+		{"Nope > 0", "field 'Nope' not found"},
+		{"C == 0", "cannot be used in an expression"},
+		{"(I > 0", "expected ')'"},
+		{"I > 0)", "unexpected ')'"},
+		{"I >", "unexpected end of expression"},
+		{"S == 'abc", "unterminated string"},
+		{"I > 0 # comment", "unexpected '#'"},
+		{"T > 0", "operator '>' is not applicable to time and number"},
+	}
+	for _, tc := range testCases {
+		_, err := compileExpr(refType, directive{name: "expr", arg: tc.expr})
+		assert.ErrorContains(t, err, tc.err, tc.expr)
+	}
+
+	// Reported ahead of time
+	type marker struct {
+		_ struct{} `dv8:"expr I > 'a',required"`
+		I int
+	}
+	err := Check(reflect.TypeOf(marker{}))
+	if assert.Error(t, err) {
+		assert.Len(t, err.(Errors), 2)
+		assert.ErrorContains(t, err, "field '_'")
+		assert.ErrorContains(t, err, "operator '>' is not applicable to number and string")
+		assert.ErrorContains(t, err, "directive 'required' is not applicable")
+	}
+}
+
+type exprEmbedded struct {
+	Zip string
+}
+
+func TestExpr_NilEmbedded(t *testing.T) {
+	x := struct {
+		_ struct{} `dv8:"expr Zip != 'x'"`
+		*exprEmbedded
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.exprEmbedded = &exprEmbedded{Zip: "x"}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Zip != 'x'")
+}
+
+func TestExpr_DryRun(t *testing.T) {
+	x := struct {
+		_        struct{} `dv8:"expr Discount <= Subtotal * 0.5"`
+		Subtotal float64
+		Discount float64 `dv8:"default=100"`
+	}{
+		Subtotal: 100,
+	}
+	err := ValidateOptions(context.Background(), &x, Options{DryRun: true})
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, x.Discount)
+
+	err = Validate(&x)
+	assert.Error(t, err)
+	assert.Equal(t, 100.0, x.Discount)
+}
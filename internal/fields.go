@@ -0,0 +1,212 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+/*
+selection is the tree of fields selected for validation, parsed from their paths.
+A field that is selected in whole is validated along with everything it contains.
+Otherwise, only the selected fields nested in it are validated.
+
+Example:
+
+	Address.Zip
+	Items[*].Qty
+	Items[0]
+*/
+type selection struct {
+	all    bool                  // The value is selected in whole
+	fields map[string]*selection // Selected fields of a struct, by name
+	items  map[string]*selection // Selected items of an array or map, by index or key, or * for all items
+}
+
+// whole returns true if the value is selected in whole.
+// A nil selection selects the entire data.
+func (s *selection) whole() bool {
+	return s == nil || s.all
+}
+
+// field returns the selection of the field of a struct, or nil if the field is not selected.
+// Fields are matched by their Go name, or by their name in the tag with the given key.
+func (s *selection) field(seg PathSegment, tagKey string) *selection {
+	if sel, ok := s.fields[seg.Field]; ok {
+		return sel
+	}
+	if seg.Flattened(tagKey) {
+		// The fields of a flattened embedded struct are selected by their names as fields of the parent
+		return s
+	}
+	if tagKey != "" {
+		return s.fields[seg.Name(tagKey)]
+	}
+	return nil
+}
+
+// item returns the selection of the item of an array or map, or nil if the item is not selected.
+// If no items are selected explicitly, the selection applies to all items,
+// so that "Items.Qty" is the same as "Items[*].Qty".
+func (s *selection) item(index any) *selection {
+	if len(s.items) == 0 {
+		return s
+	}
+	if sel, ok := s.items[fmt.Sprintf("%v", index)]; ok {
+		return sel
+	}
+	return s.items["*"]
+}
+
+// selectFields parses the paths of the fields into a selection, and verifies them against the type.
+func selectFields(refType reflect.Type, paths []string, tagKey string) (*selection, error) {
+	root := &selection{}
+	for _, path := range paths {
+		sel := root
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		err = checkPath(refType, steps, tagKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path '%s': %w", path, err)
+		}
+		for _, step := range steps {
+			var children *map[string]*selection
+			name := step
+			if strings.HasPrefix(step, "[") {
+				children = &sel.items
+				name = step[1 : len(step)-1]
+			} else {
+				children = &sel.fields
+			}
+			if *children == nil {
+				*children = map[string]*selection{}
+			}
+			child, ok := (*children)[name]
+			if !ok {
+				child = &selection{}
+				(*children)[name] = child
+			}
+			sel = child
+		}
+		sel.all = true
+	}
+	return root, nil
+}
+
+// parsePath breaks a path such as "Items[*].Qty" into its steps, e.g. "Items", "[*]" and "Qty".
+func parsePath(path string) (steps []string, err error) {
+	i := 0
+	for i < len(path) {
+		if path[i] == '[' {
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path '%s': unterminated bracket at column %d", path, i+1)
+			}
+			steps = append(steps, path[i:i+end+1])
+			i += end + 1
+			continue
+		}
+		if path[i] == '.' && len(steps) > 0 {
+			i++
+		}
+		start := i
+		for i < len(path) && path[i] != '.' && path[i] != '[' {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("invalid path '%s': expected field name at column %d", path, i+1)
+		}
+		steps = append(steps, path[start:i])
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid path '%s'", path)
+	}
+	return steps, nil
+}
+
+// checkPath verifies that the steps of a path lead to a field of the type.
+func checkPath(refType reflect.Type, steps []string, tagKey string) error {
+	for _, step := range steps {
+		for refType.Kind() == reflect.Pointer {
+			refType = refType.Elem()
+		}
+		switch refType.Kind() {
+		case reflect.Interface:
+			// The type of the dynamic value is not known ahead of time
+			return nil
+		case reflect.Array, reflect.Slice, reflect.Map:
+			refType = refType.Elem()
+			if strings.HasPrefix(step, "[") {
+				continue
+			}
+			// The field applies to all items
+			for refType.Kind() == reflect.Pointer {
+				refType = refType.Elem()
+			}
+		}
+		if strings.HasPrefix(step, "[") {
+			return fmt.Errorf("'%s' is not an array or map", refType)
+		}
+		if refType.Kind() == reflect.Interface {
+			return nil
+		}
+		if refType.Kind() != reflect.Struct {
+			return fmt.Errorf("'%s' is not a struct", refType)
+		}
+		fldType, ok := findField(refType, step, tagKey, map[reflect.Type]bool{})
+		if !ok {
+			return fmt.Errorf("field '%s' not found in '%v'", step, refType)
+		}
+		refType = fldType
+	}
+	return nil
+}
+
+// findField returns the type of the field of the struct with the given name,
+// looking into the embedded structs that are flattened when rendered with the tag key.
+// The visited structs are tracked to guard against recursive embedding.
+func findField(refType reflect.Type, name string, tagKey string, visited map[reflect.Type]bool) (reflect.Type, bool) {
+	visited[refType] = true
+	for i := 0; i < refType.NumField(); i++ {
+		fld := refType.Field(i)
+		seg := PathSegment{Field: fld.Name, Tag: fld.Tag, Embedded: embeddedStruct(fld)}
+		if seg.Field == name || (tagKey != "" && seg.Name(tagKey) == name) {
+			return fld.Type, true
+		}
+	}
+	for i := 0; i < refType.NumField(); i++ {
+		fld := refType.Field(i)
+		seg := PathSegment{Field: fld.Name, Tag: fld.Tag, Embedded: embeddedStruct(fld)}
+		if !seg.Flattened(tagKey) {
+			continue
+		}
+		embedded := fld.Type
+		if embedded.Kind() == reflect.Pointer {
+			embedded = embedded.Elem()
+		}
+		if visited[embedded] {
+			continue
+		}
+		if fldType, ok := findField(embedded, name, tagKey, visited); ok {
+			return fldType, true
+		}
+	}
+	return nil, false
+}
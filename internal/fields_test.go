@@ -0,0 +1,166 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fieldsAddress struct {
+	Street string `json:"street" dv8:"required"`
+	Zip    string `json:"zip" dv8:"required,len==5"`
+}
+
+func (a *fieldsAddress) Validate() error {
+	if a.Zip == "00000" {
+		return errors.New("invalid zip")
+	}
+	return nil
+}
+
+type fieldsItem struct {
+	SKU string `json:"sku" dv8:"required"`
+	Qty int    `json:"qty" dv8:"val>0"`
+}
+
+type fieldsOrder struct {
+	ID      int               `json:"id" dv8:"required"`
+	Name    string            `json:"name" dv8:"required,toupper"`
+	Address *fieldsAddress    `json:"address"`
+	Items   []fieldsItem      `json:"items"`
+	Notes   map[string]string `json:"notes" dv8:"len<=8"`
+}
+
+func TestFields_Partial(t *testing.T) {
+	ctx := context.Background()
+	x := fieldsOrder{
+		Name: " jane ",
+		Address: &fieldsAddress{
+			Zip: "123",
+		},
+		Items: []fieldsItem{
+			{Qty: 1},
+			{Qty: 0},
+		},
+		Notes: map[string]string{"a": "short", "b": "too long for a note"},
+	}
+
+	// Only the name is validated and normalized
+	err := ValidateOptions(ctx, &x, Options{Fields: []string{"Name"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "JANE", x.Name)
+
+	// Nested fields
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Address.Zip"}})
+	assert.ErrorContains(t, err, "Address: Zip: length must equal 5")
+	x.Address.Zip = "00000"
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Address.Zip"}})
+	assert.ErrorContains(t, err, "Address: invalid zip")
+	x.Address.Zip = "12345"
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Address.Zip"}})
+	assert.NoError(t, err)
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Address"}})
+	assert.ErrorContains(t, err, "Address: Street: value is required")
+
+	// Items of arrays
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Items[*].Qty"}})
+	assert.ErrorContains(t, err, "Items: [1]: Qty: must be greater than 0")
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Items.Qty"}})
+	assert.ErrorContains(t, err, "Items: [1]: Qty: must be greater than 0")
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Items[0].Qty"}})
+	assert.NoError(t, err)
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Items[0]"}})
+	assert.ErrorContains(t, err, "Items: [0]: SKU: value is required")
+
+	// Items of maps
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Notes[a]"}})
+	assert.NoError(t, err)
+	err = ValidateOptions(ctx, &x, Options{Fields: []string{"Notes[b]"}})
+	assert.ErrorContains(t, err, "Notes: [b]: length")
+
+	// Names in the tag of the path style
+	err = ValidateOptions(ctx, &x, Options{
+		Fields:     []string{"items[*].qty", "address.zip"},
+		PathStyle:  PathStyleJSONPointer,
+		PathTagKey: "json",
+		All:        true,
+	})
+	if assert.Error(t, err) {
+		assert.Len(t, err.(Errors), 1)
+		assert.ErrorContains(t, err, "/items/1/qty: must be greater than 0")
+	}
+}
+
+func TestFields_InvalidPaths(t *testing.T) {
+	ctx := context.Background()
+	x := fieldsOrder{}
+	for _, path := range []string{"Nope", "Address.Nope", "Name.Nope", "Name[0]", "Items[*", "Items..Qty", "."} {
+		err := ValidateOptions(ctx, &x, Options{Fields: []string{path}})
+		assert.ErrorContains(t, err, "invalid path '"+path+"'", path)
+	}
+}
+
+func TestFields_On(t *testing.T) {
+	type address struct {
+		City string `dv8:"required"`
+		Zip  string
+	}
+	type order struct {
+		Address address `dv8:"required,on Zip"`
+	}
+	x := order{
+		Address: address{City: "Springfield"},
+	}
+	err := ValidateOptions(context.Background(), &x, Options{Fields: []string{"Address.City"}})
+	assert.NoError(t, err)
+
+	err = ValidateOptions(context.Background(), &x, Options{Fields: []string{"Address.Zip"}})
+	assert.ErrorContains(t, err, "value is required")
+
+	err = ValidateOptions(context.Background(), &x, Options{Fields: []string{"Address"}})
+	assert.ErrorContains(t, err, "value is required")
+}
+
+func TestFields_Embedded(t *testing.T) {
+	type base struct {
+		ID   int    `json:"id" dv8:"required"`
+		Kind string `json:"kind" dv8:"required"`
+	}
+	type doc struct {
+		base
+		Name string `json:"name"`
+	}
+	x := doc{base: base{Kind: "memo"}}
+	err := ValidateOptions(context.Background(), &x, Options{
+		Fields:     []string{"id"},
+		PathStyle:  PathStyleJSONPointer,
+		PathTagKey: "json",
+	})
+	assert.EqualError(t, err, "/id: non-zero value is required")
+
+	err = ValidateOptions(context.Background(), &x, Options{
+		Fields:     []string{"kind", "name"},
+		PathTagKey: "json",
+	})
+	assert.NoError(t, err)
+
+	err = ValidateOptions(context.Background(), &x, Options{Fields: []string{"base.ID"}})
+	assert.ErrorContains(t, err, "base: ID: non-zero value is required")
+}
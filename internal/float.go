@@ -16,77 +16,90 @@ limitations under the License.
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 )
 
-// validateFloat validates the value of a floating point number against the tags.
-func validateFloat(refVal reflect.Value, tags []string) (err error) {
-	f := refVal.Float()
+// floatDirectives are the directives applicable to a floating point number.
+var floatDirectives = []string{"required", "optional", "default", "val"}
+
+// compileFloat compiles the directives that apply to a floating point number.
+func compileFloat(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if f == 0 && strings.HasPrefix(t, "default=") {
-			def, err := strconv.ParseFloat(t[len("default="):], 64)
+		case "default":
+			def, err := strconv.ParseFloat(d.arg, 64)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if def != f {
-				f = def
-				changed = true
+			if def == 0 {
+				continue
 			}
+			defVal := reflect.ValueOf(def).Convert(p.refType)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.Float() != 0 {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetFloat(f)
-	}
-	if f == 0 && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("non-zero value is required", func(refVal reflect.Value) bool {
+			return refVal.Float() == 0
+		}))
 	}
 	// Range constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<M
-			operator := t[3:4]
-			var v float64
-			if t[4] == '=' {
-				operator += "="
-				v, err = strconv.ParseFloat(t[5:], 64)
-			} else {
-				v, err = strconv.ParseFloat(t[4:], 64)
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "<=" && f > v:
-				err = fmt.Errorf("must be less than or equal to %f", v)
-			case operator == "<" && f >= v:
-				err = fmt.Errorf("must be less than %f", v)
-			case operator == ">=" && f < v:
-				err = fmt.Errorf("must be greater than or equal to %f", v)
-			case operator == ">" && f <= v:
-				err = fmt.Errorf("must be greater than %f", v)
-			case operator == "!=" && f == v:
-				err = fmt.Errorf("must not equal %f", v)
-			case operator == "==" && f != v:
-				err = fmt.Errorf("must equal %f", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val<M
+		operator := d.op
+		v, err := strconv.ParseFloat(d.arg, 64)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				f := refVal.Float()
+				switch {
+				case operator == "<=" && f > v:
+					return fmt.Errorf("must be less than or equal to %f", v)
+				case operator == "<" && f >= v:
+					return fmt.Errorf("must be less than %f", v)
+				case operator == ">=" && f < v:
+					return fmt.Errorf("must be greater than or equal to %f", v)
+				case operator == ">" && f <= v:
+					return fmt.Errorf("must be greater than %f", v)
+				case operator == "!=" && f == v:
+					return fmt.Errorf("must not equal %f", v)
+				case operator == "==" && f != v:
+					return fmt.Errorf("must equal %f", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
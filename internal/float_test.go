@@ -158,3 +158,15 @@ func TestFloat_Types(t *testing.T) {
 	err = Validate(&x)
 	assert.NoError(t, err)
 }
+
+func TestFloat_Optional(t *testing.T) {
+	x := struct {
+		F float64 `dv8:"optional,(val<=-1 | val>=1)"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.F = 0.5
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "value must satisfy one of")
+}
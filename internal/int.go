@@ -16,77 +16,88 @@ limitations under the License.
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 )
 
-// validateInt validates the value of a signed integer against the tags.
-func validateInt(refVal reflect.Value, tags []string) (err error) {
-	i := refVal.Int()
+// intDirectives are the directives applicable to a signed integer.
+var intDirectives = []string{"required", "optional", "default", "val"}
+
+// compileInt compiles the directives that apply to a signed integer.
+func compileInt(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if i == 0 && strings.HasPrefix(t, "default=") {
-			def, err := strconv.ParseInt(t[len("default="):], 10, 64)
+		case "default":
+			def, err := strconv.ParseInt(d.arg, 10, 64)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if def != i {
-				i = def
-				changed = true
+			if def == 0 {
+				continue
 			}
+			defVal := reflect.ValueOf(def).Convert(p.refType)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.Int() != 0 {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetInt(i)
-	}
-	if i == 0 && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("non-zero value is required", reflect.Value.IsZero))
 	}
 	// Range constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<M
-			operator := t[3:4]
-			var v int64
-			if t[4] == '=' {
-				operator += "="
-				v, err = strconv.ParseInt(t[5:], 10, 64)
-			} else {
-				v, err = strconv.ParseInt(t[4:], 10, 64)
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "<=" && i > v:
-				err = fmt.Errorf("must be less than or equal to %d", v)
-			case operator == "<" && i >= v:
-				err = fmt.Errorf("must be less than %d", v)
-			case operator == ">=" && i < v:
-				err = fmt.Errorf("must be greater than or equal to %d", v)
-			case operator == ">" && i <= v:
-				err = fmt.Errorf("must be greater than %d", v)
-			case operator == "!=" && i == v:
-				err = fmt.Errorf("must not equal %d", v)
-			case operator == "==" && i != v:
-				err = fmt.Errorf("must equal %d", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val<M
+		operator := d.op
+		v, err := strconv.ParseInt(d.arg, 10, 64)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				i := refVal.Int()
+				switch {
+				case operator == "<=" && i > v:
+					return fmt.Errorf("must be less than or equal to %d", v)
+				case operator == "<" && i >= v:
+					return fmt.Errorf("must be less than %d", v)
+				case operator == ">=" && i < v:
+					return fmt.Errorf("must be greater than or equal to %d", v)
+				case operator == ">" && i <= v:
+					return fmt.Errorf("must be greater than %d", v)
+				case operator == "!=" && i == v:
+					return fmt.Errorf("must not equal %d", v)
+				case operator == "==" && i != v:
+					return fmt.Errorf("must equal %d", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
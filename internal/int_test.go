@@ -202,3 +202,15 @@ func TestInt_Validator(t *testing.T) {
 	err = Validate(&bad)
 	assert.ErrorContains(t, err, "invalid value")
 }
+
+func TestInt_Optional(t *testing.T) {
+	x := struct {
+		N int `dv8:"optional,val>=10"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.N = 5
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "must be greater than or equal to 10")
+}
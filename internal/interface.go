@@ -0,0 +1,52 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"reflect"
+)
+
+// compileInterface compiles the directives that apply to an interface.
+// Other than nonnil and nullable, the directives apply to the dynamic value and are compiled once its type is known.
+func (c *compiler) compileInterface(p *plan, dirs []directive) (applicable []string) {
+	p.required = hasDirective(dirs, "required")
+	p.nonnil = hasDirective(dirs, "nonnil")
+	p.nullable = hasDirective(dirs, "nullable")
+	p.dynamic = without(dirs, nilDirectives)
+	// Only unknown and conflicting directives can be detected before the dynamic type is known
+	for name := range builtinDirectives {
+		applicable = append(applicable, name)
+	}
+	return applicable
+}
+
+// validateInterface validates the dynamic value of an interface according to its concrete type.
+func validateInterface(w *walk, p *plan, refVal reflect.Value) (err error) {
+	if refVal.IsNil() {
+		return validateNil(p, refVal)
+	}
+	elem := refVal.Elem()
+	dynamic := planOf(elem.Type(), p.dynamic, w.opts.planOptions())
+	if !refVal.CanSet() || !elem.CanInterface() || w.opts.DryRun {
+		return validateAny(w, dynamic, elem)
+	}
+	// The dynamic value is not addressable, so it is normalized in a copy that is then set back
+	cp := reflect.New(elem.Type()).Elem()
+	cp.Set(elem)
+	err = validateAny(w, dynamic, cp)
+	w.set(refVal, cp)
+	return err
+}
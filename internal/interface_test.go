@@ -0,0 +1,133 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type shape interface {
+	Area() int
+}
+
+type square struct {
+	Side int `dv8:"val>0"`
+}
+
+func (s square) Area() int {
+	return s.Side * s.Side
+}
+
+func (s square) Validate() error {
+	if s.Side > 100 {
+		return errors.New("too big")
+	}
+	return nil
+}
+
+func TestInterface_Required(t *testing.T) {
+	type data struct {
+		Any   any   `dv8:"nonnil"`
+		Shape shape `dv8:"required"`
+		Err   error `dv8:"required"`
+	}
+	err := Validate(&data{Shape: square{Side: 1}, Err: errors.New("x")})
+	assert.ErrorContains(t, err, "Any: non-nil value is required")
+	err = Validate(&data{Any: "", Err: errors.New("x")})
+	assert.ErrorContains(t, err, "Shape: value is required")
+	err = Validate(&data{Any: 0, Shape: square{Side: 1}, Err: errors.New("x")})
+	assert.NoError(t, err)
+
+	// Required dynamic values must also be non-zero
+	err = Validate(&data{Any: 0, Shape: square{}, Err: errors.New("x")})
+	assert.ErrorContains(t, err, "Shape: value is required")
+}
+
+func TestInterface_Dynamic(t *testing.T) {
+	type data struct {
+		Shape  shape
+		Shapes []shape
+		Name   any `dv8:"len<=5,toupper"`
+		Ptr    any
+	}
+	d := &data{Shape: square{Side: 1}}
+	err := Validate(d)
+	assert.NoError(t, err)
+
+	// Nested structs
+	d.Shape = square{Side: 0}
+	err = Validate(d)
+	assert.ErrorContains(t, err, "Shape: Side: must be greater than 0")
+	d.Shape = &square{Side: 0}
+	err = Validate(d)
+	assert.ErrorContains(t, err, "Shape: Side: must be greater than 0")
+	d.Shape = nil
+	d.Shapes = []shape{square{Side: 1}, square{Side: 0}}
+	err = Validate(d)
+	assert.ErrorContains(t, err, "Shapes: [1]: Side: must be greater than 0")
+	d.Shapes = nil
+
+	// Validator of the dynamic value
+	d.Shape = square{Side: 101}
+	err = Validate(d)
+	assert.ErrorContains(t, err, "Shape: too big")
+	d.Shape = nil
+
+	// Directives apply to the dynamic value, which is normalized in place
+	d.Name = " abc "
+	err = Validate(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "ABC", d.Name)
+	d.Name = "abcdef"
+	err = Validate(d)
+	assert.ErrorContains(t, err, "Name: length must be less than or equal to 5")
+	d.Name = nil
+
+	// Pointer to a struct
+	type person struct {
+		Name string `dv8:"default=Anonymous"`
+	}
+	p := &person{}
+	d.Ptr = p
+	err = Validate(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "Anonymous", p.Name)
+
+	// Data passed by value
+	err = Validate(data{Name: " abc "})
+	assert.ErrorContains(t, err, "data must be passed by reference")
+}
+
+func TestInterface_Strict(t *testing.T) {
+	type data struct {
+		Any any `dv8:"requried"`
+	}
+	err := Check(reflect.TypeOf(data{}))
+	assert.ErrorContains(t, err, "unknown directive 'requried'")
+
+	type data2 struct {
+		Any any `dv8:"len<=5"`
+	}
+	err = ValidateOptions(context.Background(), &data2{Any: 5}, Options{Strict: true})
+	assert.ErrorContains(t, err, "directive 'len<=5' is not applicable to 'int'")
+	err = ValidateOptions(context.Background(), &data2{Any: "abc"}, Options{Strict: true})
+	assert.NoError(t, err)
+}
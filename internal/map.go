@@ -16,74 +16,123 @@ limitations under the License.
 package internal
 
 import (
-	"context"
 	"errors"
-	"fmt"
 	"reflect"
-	"strconv"
-	"strings"
 )
 
-// validateMap validates the value of a map against the tags.
-func validateMap(ctx context.Context, refType reflect.Type, refVal reflect.Value, tags []string) (err error) {
-	// Length
-	for i, t := range tags {
-		if strings.HasPrefix(t, "maplen") && len(t) > 7 {
-			if refVal.IsNil() {
-				return errors.New("value is required")
-			}
-			// Example: maplen<8
-			operator := t[6:7]
-			var l int
-			if t[7] == '=' {
-				operator += "="
-				l, err = strconv.Atoi(t[8:])
-			} else {
-				l, err = strconv.Atoi(t[7:])
-			}
-			if err != nil {
-				return err
-			}
-			mapLen := refVal.Len()
-			switch {
-			case operator == "<=" && mapLen > l:
-				err = fmt.Errorf("length must be less than or equal to %d", l)
-			case operator == "<" && mapLen >= l:
-				err = fmt.Errorf("length must be less than %d", l)
-			case operator == ">=" && mapLen < l:
-				err = fmt.Errorf("length must be greater than or equal to %d", l)
-			case operator == ">" && mapLen <= l:
-				err = fmt.Errorf("length must be greater than %d", l)
-			case operator == "!=" && mapLen == l:
-				err = fmt.Errorf("length must not equal %d", l)
-			case operator == "==" && mapLen != l:
-				err = fmt.Errorf("length must equal %d", l)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
+// compileMap compiles the plan of a map and of its value items.
+// Except for maplen, nonnil and nullable, directives set on a map apply to its value items.
+// A required map must also be non-empty.
+// Directives are not enforced on the keys of a map.
+func (c *compiler) compileMap(p *plan, dirs []directive) error {
+	var errs Errors
+	p.required = hasDirective(dirs, "required")
+	var itemDirs []directive
+	for _, d := range dirs {
+		if contains(nilDirectives, d.name) {
+			continue
+		}
+		if d.name != "maplen" {
+			itemDirs = append(itemDirs, d)
+			continue
+		}
+		// Example: maplen<8
+		cons, err := compileLen(d, reflect.Value.Len)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		p.constraints = append(p.constraints, cons)
+	}
+	p.elem = c.compile(p.refType.Elem(), itemDirs)
+	if c.opts.strict {
+		errs = appendUnique(errs, p.elem.err)
+	}
+	return errs.join()
+}
+
+// validateMap validates the value of a map against its plan.
+func validateMap(w *walk, p *plan, refVal reflect.Value) (err error) {
+	var errs Errors
+	// Nil and length
+	if refVal.IsNil() {
+		err = validateNil(p, refVal)
+		if err != nil {
+			return err
+		}
+	}
+	if p.required && refVal.Len() == 0 {
+		return &FieldError{
+			Directive: "required",
+			Value:     valueOf(refVal),
+			Err:       errors.New("non-empty value is required"),
+		}
+	}
+	constraints := p.constraints
+	if p.optional && refVal.Len() == 0 || p.nullable && refVal.IsNil() {
+		// Empty optional maps and nil nullable maps are not constrained
+		constraints = nil
+	}
+	if len(constraints) > 0 && refVal.IsNil() {
+		return &FieldError{
+			Directive: "maplen",
+			Value:     valueOf(refVal),
+			Err:       errors.New("value is required"),
+		}
+	}
+	for _, c := range constraints {
+		err = c.check(refVal)
+		if err != nil {
+			err = &FieldError{
+				Directive: c.directive,
+				Operator:  c.operator,
+				Param:     c.param,
+				Value:     valueOf(refVal),
+				Err:       err,
 			}
-			if err != nil {
+			if !w.opts.All {
 				return err
 			}
-			tags[i] = "" // Do not apply to items
+			errs = errs.collect(err)
 		}
 	}
 	// Nested elements
-	mapType := refType.Elem()
+	settable := refVal.CanSet() && !w.opts.DryRun
+	sel := w.sel
+	defer func() { w.sel = sel }()
 	iter := refVal.MapRange()
 	for iter.Next() {
+		if !sel.whole() {
+			// Only the selected items are validated
+			w.sel = sel.item(iter.Key().Interface())
+			if w.sel == nil {
+				continue
+			}
+		}
 		val := iter.Value()
-		if refVal.CanSet() {
+		if settable {
 			// Create an addressable copy of the value item
-			val = reflect.New(mapType).Elem()
+			val = reflect.New(p.elem.refType).Elem()
 			val.Set(iter.Value())
 		}
-		err = validateAny(ctx, mapType, val, tags)
+		if w.report {
+			// Avoid the cost of boxing the key unless reporting changes
+			w.push(PathSegment{Index: iter.Key().Interface()})
+		}
+		err = validateAny(w, p.elem, val)
+		if w.report {
+			w.pop()
+		}
 		if err != nil {
-			return fmt.Errorf("[%v]: %w", iter.Key(), err)
+			err = atPath(PathSegment{Index: iter.Key().Interface()}, err)
+			if !w.opts.All {
+				return err
+			}
+			errs = errs.collect(err)
 		}
-		if refVal.CanSet() {
-			refVal.SetMapIndex(iter.Key(), val)
+		if settable {
+			w.setMapIndex(refVal, iter.Key(), val)
 		}
 	}
-	return nil
+	return errs.orNil()
 }
@@ -23,7 +23,7 @@ import (
 
 func TestMap_Pointer(t *testing.T) {
 	x := struct {
-		M *map[int]int `dv8:"required"`
+		M *map[int]int `dv8:"nonnil"`
 	}{}
 	m := map[int]int{}
 	x.M = &m
@@ -32,7 +32,18 @@ func TestMap_Pointer(t *testing.T) {
 
 	x.M = nil
 	err = Validate(&x)
-	assert.ErrorContains(t, err, "required")
+	assert.ErrorContains(t, err, "non-nil value is required")
+
+	y := struct {
+		M *map[int]int `dv8:"required"`
+	}{}
+	y.M = &m
+	err = Validate(&y)
+	assert.ErrorContains(t, err, "non-empty value is required")
+
+	m[1] = 1
+	err = Validate(&y)
+	assert.NoError(t, err)
 }
 
 func TestMap_Nesting(t *testing.T) {
@@ -175,3 +186,43 @@ func TestMap_Items(t *testing.T) {
 	err = Validate(&items)
 	assert.ErrorContains(t, err, "length")
 }
+
+func TestMap_Optional(t *testing.T) {
+	x := struct {
+		M map[string]int `dv8:"optional,maplen>=2"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.M = map[string]int{}
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.M = map[string]int{"a": 1}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "length must be greater than or equal to 2")
+}
+
+func TestMap_NilDirectives(t *testing.T) {
+	x := struct {
+		NonNil   map[int]int `dv8:"nonnil"`
+		Nullable map[int]int `dv8:"nullable,maplen>=1"`
+	}{
+		NonNil: map[int]int{},
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.NonNil = nil
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "NonNil: non-nil value is required")
+
+	x.NonNil = map[int]int{}
+	x.Nullable = map[int]int{}
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Nullable: length must be greater than or equal to 1")
+
+	x.Nullable = map[int]int{1: 1}
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,633 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	timeType             = reflect.TypeOf(time.Time{})
+	durationType         = reflect.TypeOf(time.Duration(0))
+	validatorType        = reflect.TypeOf((*Validator)(nil)).Elem()
+	validatorContextType = reflect.TypeOf((*ValidatorContext)(nil)).Elem()
+)
+
+// hasDirective returns true if the directive with the given name is present.
+func hasDirective(dirs []directive, name string) bool {
+	for _, d := range dirs {
+		if d.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// joinDirectives joins the directives back into a tag.
+func joinDirectives(dirs []directive) string {
+	if len(dirs) == 0 {
+		return ""
+	}
+	if len(dirs) == 1 {
+		return dirs[0].raw
+	}
+	var b strings.Builder
+	for i, d := range dirs {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(d.raw)
+	}
+	return b.String()
+}
+
+// planKind determines how a plan walks a value.
+type planKind int
+
+const (
+	kindNone planKind = iota
+	kindScalar
+	kindPointer
+	kindStruct
+	kindArray
+	kindMap
+	kindInterface
+	kindType
+)
+
+// normalizer is a compiled directive that transforms a value, e.g. "default=CA" or "toupper".
+type normalizer struct {
+	directive string
+	apply     func(refVal reflect.Value) (normalized reflect.Value, changed bool)
+}
+
+// constraint is a compiled directive that checks a value without modifying it, e.g. "len<=32".
+type constraint struct {
+	directive string
+	operator  string
+	param     string
+	check     func(refVal reflect.Value) error
+}
+
+// custom is a compiled custom directive.
+type custom struct {
+	directive
+	fn DirectiveFunc
+}
+
+// fieldPlan is the plan of a field of a struct.
+type fieldPlan struct {
+	index []int       // Index of the field, as used by FieldByIndex
+	seg   PathSegment // Path segment of the field
+	main  *plan       // Plan of the directives of the parent struct, for fields marked with main
+	plan  *plan       // Plan of the directives of the field
+	conds []condition // Directives that reference sibling fields
+}
+
+/*
+plan is the compiled form of the directives that apply to values of a given type.
+Plans are compiled once per type and directives, cached, and are immutable thereafter.
+*/
+type plan struct {
+	refType reflect.Type
+	kind    planKind
+	err     error // Compilation error, returned when a value is validated
+
+	required    bool         // Required pointer, struct, interface, slice or map
+	nonnil      bool         // Non-nil pointer, interface, slice or map
+	nullable    bool         // Nil slice or map that is not constrained
+	optional    bool         // Constraints of scalars, arrays and maps are skipped for zero values
+	normalizers []normalizer // Normalizations of scalars, in order of appearance
+	constraints []constraint // Constraints of scalars, arrays and maps, in order of appearance
+	custom      []custom     // Custom directives, in order of appearance
+
+	typeFunc TypeFunc // Function of a registered type
+	tags     string   // Directives passed to the function of a registered type
+
+	dynamic []directive // Directives of an interface, compiled once the type of the dynamic value is known
+
+	elem   *plan        // Plan of the target of a pointer, or the items of an array or map
+	on     []*fieldPlan // Fields of a struct that the directives are pushed down to
+	fields []*fieldPlan // Fields of a struct
+	exprs  []expression // Expressions of a struct, evaluated after its fields
+
+	validator           bool // Type implements Validator
+	validatorPtr        bool // Pointer to type implements Validator
+	validatorContext    bool // Type implements ValidatorContext
+	validatorContextPtr bool // Pointer to type implements ValidatorContext
+}
+
+// planOptions are the options that affect the compilation of plans.
+type planOptions struct {
+	strict  bool   // Reject unknown, inapplicable or conflicting directives
+	tagKeys string // Comma-separated tag keys to look up in order, or empty for "dv8"
+	noTrim  bool   // Do not trim strings unless the trim directive is present
+	groups  string // Comma-separated active validation groups, or * for all groups
+}
+
+// inGroups returns the directives that apply to the active validation groups.
+// Directives that are not limited to groups apply to all groups.
+func (opts planOptions) inGroups(dirs []directive) []directive {
+	if opts.groups == "*" {
+		return dirs
+	}
+	groups := strings.Split(opts.groups, ",")
+	var active []directive
+	for _, d := range dirs {
+		if len(d.groups) == 0 || intersects(d.groups, groups) {
+			active = append(active, d)
+		}
+	}
+	return active
+}
+
+// intersects returns true if the two lists have a value in common.
+func intersects(a []string, b []string) bool {
+	for _, v := range a {
+		if contains(b, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagOf returns the directives of the field from the first tag key that is present.
+func (opts planOptions) tagOf(fld reflect.StructField) string {
+	if opts.tagKeys == "" {
+		return fld.Tag.Get("dv8")
+	}
+	for _, key := range strings.Split(opts.tagKeys, ",") {
+		if tagVal, ok := fld.Tag.Lookup(key); ok {
+			return tagVal
+		}
+	}
+	return ""
+}
+
+// planKey is the key of a plan in the cache.
+type planKey struct {
+	refType reflect.Type
+	tags    string
+	opts    planOptions
+}
+
+var (
+	plans    sync.Map // planKey -> *plan
+	plansMux sync.Mutex
+)
+
+// planOf returns the plan to validate values of the type against the directives, compiling it if necessary.
+func planOf(refType reflect.Type, dirs []directive, opts planOptions) *plan {
+	key := planKey{refType: refType, tags: joinDirectives(dirs), opts: opts}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan)
+	}
+	plansMux.Lock()
+	defer plansMux.Unlock()
+	c := compiler{
+		opts:     opts,
+		compiled: map[planKey]*plan{},
+	}
+	p := c.compile(refType, dirs)
+	// Publish only after all plans are complete
+	for k, v := range c.compiled {
+		plans.Store(k, v)
+	}
+	return p
+}
+
+// resetPlans clears the cache of plans, forcing them to be compiled again.
+func resetPlans() {
+	plansMux.Lock()
+	defer plansMux.Unlock()
+	plans.Range(func(key, value any) bool {
+		plans.Delete(key)
+		return true
+	})
+}
+
+// compiler compiles plans recursively.
+type compiler struct {
+	opts     planOptions
+	compiled map[planKey]*plan
+}
+
+// compile returns the plan to validate values of the type against the directives.
+// In strict mode, the plan fails to compile if any of its nested plans fails to compile.
+func (c *compiler) compile(refType reflect.Type, dirs []directive) *plan {
+	key := planKey{refType: refType, tags: joinDirectives(dirs), opts: c.opts}
+	if p, ok := plans.Load(key); ok {
+		return p.(*plan)
+	}
+	if p, ok := c.compiled[key]; ok {
+		// May still be under construction if the type is recursive
+		return p
+	}
+	p := &plan{
+		refType: refType,
+	}
+	c.compiled[key] = p
+
+	var err error
+	var applicable []string // Directives that apply to the type, or nil if they are pushed down
+	fn := typeFunc(refType)
+	switch {
+	case fn != nil:
+		// Registered types take precedence and interpret the directives on their own
+		p.kind = kindType
+		p.typeFunc = fn
+		p.tags = key.tags
+	case refType == durationType:
+		p.kind = kindScalar
+		applicable = durationDirectives
+		err = compileDuration(p, dirs)
+	case refType == timeType:
+		p.kind = kindScalar
+		applicable = timeDirectives
+		err = compileTime(p, dirs)
+	default:
+		switch refType.Kind() {
+		case reflect.String:
+			p.kind = kindScalar
+			applicable = stringDirectives
+			err = compileString(p, dirs, !c.opts.noTrim)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			p.kind = kindScalar
+			applicable = intDirectives
+			err = compileInt(p, dirs)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			p.kind = kindScalar
+			applicable = uintDirectives
+			err = compileUint(p, dirs)
+		case reflect.Float32, reflect.Float64:
+			p.kind = kindScalar
+			applicable = floatDirectives
+			err = compileFloat(p, dirs)
+		case reflect.Bool:
+			p.kind = kindScalar
+			applicable = boolDirectives
+			err = compileBool(p, dirs)
+		case reflect.Pointer:
+			p.kind = kindPointer
+			err = c.compilePointer(p, dirs)
+		case reflect.Struct:
+			p.kind = kindStruct
+			applicable, err = c.compileStruct(p, dirs)
+		case reflect.Map:
+			p.kind = kindMap
+			err = c.compileMap(p, dirs)
+		case reflect.Array, reflect.Slice:
+			p.kind = kindArray
+			err = c.compileArray(p, dirs)
+		case reflect.Interface:
+			p.kind = kindInterface
+			applicable = c.compileInterface(p, dirs)
+		default:
+			applicable = []string{}
+		}
+	}
+	switch p.kind {
+	case kindPointer, kindArray, kindMap, kindInterface:
+		// Custom directives are pushed down to the elements
+	case kindType:
+		// The function of the registered type interprets all directives
+	default:
+		err = appendUnique(appendUnique(nil, err), compileCustom(p, dirs)).join()
+	}
+	switch p.kind {
+	case kindScalar, kindArray, kindMap:
+		p.optional = hasDirective(dirs, "optional")
+	}
+	switch p.kind {
+	case kindPointer, kindArray, kindMap:
+		// The directives that concern nil are not pushed down, so their conflicts are checked here
+		p.nonnil = hasDirective(dirs, "nonnil")
+		p.nullable = hasDirective(dirs, "nullable")
+		if c.opts.strict {
+			errs := appendUnique(nil, err)
+			errs = append(errs, checkConflicts(dirs, nilConflicts)...)
+			err = errs.join()
+		}
+	}
+	checked := dirs
+	if p.kind == kindScalar {
+		// Alternatives and negations are checked when their own plans are compiled
+		err = appendUnique(appendUnique(nil, err), c.compileComposites(p, dirs)).join()
+		checked = plain(dirs)
+	}
+	if c.opts.strict && applicable != nil {
+		var errs Errors
+		errs = appendUnique(errs, checkDirectives(refType, checked, applicable))
+		errs = appendUnique(errs, err)
+		err = errs.join()
+	}
+	p.err = err
+
+	// The methods of a pointer or an interface are those of its target, which is validated separately
+	if p.kind != kindPointer && p.kind != kindInterface {
+		ptrType := reflect.PointerTo(refType)
+		p.validator = refType.Implements(validatorType)
+		p.validatorPtr = ptrType.Implements(validatorType)
+		p.validatorContext = refType.Implements(validatorContextType)
+		p.validatorContextPtr = ptrType.Implements(validatorContextType)
+	}
+	return p
+}
+
+// hasValidators returns true if the type or a pointer to it implements Validator or ValidatorContext.
+func (p *plan) hasValidators() bool {
+	return p.validator || p.validatorPtr || p.validatorContext || p.validatorContextPtr
+}
+
+// callValidators calls the Validate and ValidateContext methods of the value, if implemented.
+func callValidators(ctx context.Context, p *plan, refVal reflect.Value) error {
+	if !refVal.CanInterface() {
+		return nil
+	}
+	var validator Validator
+	var validatorCtx ValidatorContext
+	switch {
+	case refVal.CanAddr():
+		if p.validatorPtr || p.validatorContextPtr {
+			underlyingPtr := refVal.Addr().Interface()
+			validator, _ = underlyingPtr.(Validator)
+			validatorCtx, _ = underlyingPtr.(ValidatorContext)
+		}
+	default:
+		if p.validator || p.validatorContext {
+			underlying := refVal.Interface()
+			validator, _ = underlying.(Validator)
+			validatorCtx, _ = underlying.(ValidatorContext)
+		}
+	}
+	if validator != nil {
+		err := validator.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	if validatorCtx != nil {
+		err := validatorCtx.ValidateContext(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// valueOf returns the value as an interface, even if it was obtained from an unexported field.
+func valueOf(refVal reflect.Value) any {
+	if refVal.CanInterface() {
+		return refVal.Interface()
+	}
+	switch refVal.Kind() {
+	case reflect.String:
+		return refVal.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return refVal.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return refVal.Uint()
+	case reflect.Float32, reflect.Float64:
+		return refVal.Float()
+	case reflect.Bool:
+		return refVal.Bool()
+	}
+	return nil
+}
+
+// builtinDirectives are the names of all directives recognized by DV8.
+var builtinDirectives = map[string]bool{
+	"-":        true,
+	"main":     true,
+	"on":       true,
+	"required": true,
+	"optional": true,
+	"nonnil":   true,
+	"nullable": true,
+	"default":  true,
+	"val":      true,
+	"len":      true,
+	"oneof":    true,
+	"regexp":   true,
+	"trim":     true,
+	"notrim":   true,
+	"toupper":  true,
+	"tolower":  true,
+	"arrlen":   true,
+	"maplen":   true,
+
+	"required_if":      true,
+	"required_unless":  true,
+	"required_with":    true,
+	"required_without": true,
+
+	"expr":     true,
+	"eqfield":  true,
+	"nefield":  true,
+	"gtfield":  true,
+	"gtefield": true,
+	"ltfield":  true,
+	"ltefield": true,
+}
+
+// conflictingDirectives are pairs of directives that cannot be used together.
+var conflictingDirectives = append([][2]string{
+	{"tolower", "toupper"},
+	{"trim", "notrim"},
+	{"optional", "required"},
+}, nilConflicts...)
+
+// nilDirectives are the directives that determine whether a pointer, interface, slice or map may be nil.
+// They apply to the value itself and are not pushed down to its target or items.
+var nilDirectives = []string{"nonnil", "nullable"}
+
+// nilConflicts are pairs of directives that cannot be used together on a pointer, interface, slice or map.
+var nilConflicts = [][2]string{
+	{"nullable", "nonnil"},
+	{"nullable", "required"},
+}
+
+// checkDirectives returns an error for each of the directives that is unknown,
+// not applicable to the type, or in conflict with another directive.
+func checkDirectives(refType reflect.Type, dirs []directive, applicable []string) error {
+	var errs Errors
+	var defaults []directive
+	for _, d := range dirs {
+		switch {
+		case d.name == "-" || d.name == "main":
+		case isComposite(d):
+			errs = append(errs, fmt.Errorf("directive '%s' is not applicable to '%v'", d.raw, refType))
+		case customDirective(d.name) != nil:
+			// Custom directives apply to any type
+		case !builtinDirectives[d.name]:
+			errs = append(errs, fmt.Errorf("unknown directive '%s'", d.raw))
+		case !contains(applicable, d.name):
+			errs = append(errs, fmt.Errorf("directive '%s' is not applicable to '%v'", d.raw, refType))
+		}
+		if d.name == "default" {
+			defaults = append(defaults, d)
+		}
+	}
+	if conflicting(defaults, defaults) {
+		errs = append(errs, errors.New("conflicting directives: multiple defaults"))
+	}
+	errs = append(errs, checkConflicts(dirs, conflictingDirectives)...)
+	return errs.orNil()
+}
+
+// checkConflicts returns an error for each of the pairs of directives that are used together.
+func checkConflicts(dirs []directive, pairs [][2]string) Errors {
+	var errs Errors
+	for _, pair := range pairs {
+		if conflicting(named(dirs, pair[0]), named(dirs, pair[1])) {
+			errs = append(errs, fmt.Errorf("conflicting directives '%s' and '%s'", pair[0], pair[1]))
+		}
+	}
+	return errs
+}
+
+// without returns the directives other than those with the given names.
+func without(dirs []directive, names []string) []directive {
+	var result []directive
+	for _, d := range dirs {
+		if !contains(names, d.name) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// validateNil returns an error if the nil value of a pointer, interface, slice or map is required or must not be nil.
+func validateNil(p *plan, refVal reflect.Value) error {
+	switch {
+	case p.required:
+		return &FieldError{
+			Directive: "required",
+			Value:     valueOf(refVal),
+			Err:       errors.New("value is required"),
+		}
+	case p.nonnil:
+		return &FieldError{
+			Directive: "nonnil",
+			Value:     valueOf(refVal),
+			Err:       errors.New("non-nil value is required"),
+		}
+	}
+	return nil
+}
+
+// named returns the directives with the given name.
+func named(dirs []directive, name string) []directive {
+	var result []directive
+	for _, d := range dirs {
+		if d.name == name {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// conflicting returns true if two distinct directives, one of each list, apply to a common validation group.
+func conflicting(a []directive, b []directive) bool {
+	for i := range a {
+		for j := range b {
+			if &a[i] == &b[j] {
+				continue
+			}
+			if len(a[i].groups) == 0 || len(b[j].groups) == 0 || intersects(a[i].groups, b[j].groups) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contains returns true if the value is in the list.
+func contains(list []string, val string) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// compileError creates the error returned when a directive fails to compile.
+func compileError(d directive, err error) error {
+	return &FieldError{
+		Directive: d.name,
+		Operator:  d.op,
+		Param:     d.arg,
+		Err:       err,
+	}
+}
+
+// compileLen compiles a directive that constrains a length, e.g. "len<=32" or "arrlen>0".
+func compileLen(d directive, length func(refVal reflect.Value) int) (constraint, error) {
+	l, err := strconv.Atoi(d.arg)
+	if err != nil {
+		return constraint{}, compileError(d, err)
+	}
+	operator := d.op
+	switch operator {
+	case "<=", "<", ">=", ">", "!=", "==":
+	default:
+		return constraint{}, compileError(d, fmt.Errorf("unsupported operator '%s'", operator))
+	}
+	return constraint{
+		directive: d.name,
+		operator:  operator,
+		param:     d.arg,
+		check: func(refVal reflect.Value) error {
+			n := length(refVal)
+			switch {
+			case operator == "<=" && n > l:
+				return fmt.Errorf("length must be less than or equal to %d", l)
+			case operator == "<" && n >= l:
+				return fmt.Errorf("length must be less than %d", l)
+			case operator == ">=" && n < l:
+				return fmt.Errorf("length must be greater than or equal to %d", l)
+			case operator == ">" && n <= l:
+				return fmt.Errorf("length must be greater than %d", l)
+			case operator == "!=" && n == l:
+				return fmt.Errorf("length must not equal %d", l)
+			case operator == "==" && n != l:
+				return fmt.Errorf("length must equal %d", l)
+			}
+			return nil
+		},
+	}, nil
+}
+
+// requiredConstraint compiles the required directive of a scalar.
+func requiredConstraint(msg string, isZero func(refVal reflect.Value) bool) constraint {
+	return constraint{
+		directive: "required",
+		check: func(refVal reflect.Value) error {
+			if isZero(refVal) {
+				return errors.New(msg)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlan_Cached(t *testing.T) {
+	type person struct {
+		Name string `dv8:"required,regexp ^[A-Z]"`
+	}
+	p1 := planOf(reflect.TypeOf(person{}), nil, planOptions{})
+	p2 := planOf(reflect.TypeOf(person{}), nil, planOptions{})
+	assert.True(t, p1 == p2)
+	dirs, err := parseTag("required,regexp ^[A-Z]")
+	assert.NoError(t, err)
+	assert.True(t, p1.fields[0].plan == planOf(reflect.TypeOf(""), dirs, planOptions{}))
+
+	p3 := planOf(reflect.TypeOf(&person{}), nil, planOptions{})
+	assert.True(t, p1 == p3.elem)
+}
+
+type recursive struct {
+	Name     string `dv8:"required"`
+	Children []*recursive
+}
+
+func TestPlan_Recursive(t *testing.T) {
+	x := recursive{
+		Name: "root",
+		Children: []*recursive{
+			{Name: "a"},
+			{Name: "b", Children: []*recursive{{Name: " c "}}},
+		},
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "c", x.Children[1].Children[0].Name)
+
+	x.Children[1].Children[0].Name = ""
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "Children: [1]: Children: [0]: Name: value is required")
+
+	p := planOf(reflect.TypeOf(x), nil, planOptions{})
+	assert.True(t, p == p.fields[1].plan.elem.elem)
+}
+
+func TestPlan_Concurrent(t *testing.T) {
+	type item struct {
+		SKU string `dv8:"required,toupper,regexp ^[A-Z]{3}-[0-9]{3}$"`
+		Qty int    `dv8:"val>0,default=1"`
+	}
+	type order struct {
+		Items []item `dv8:"arrlen>0"`
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				x := order{Items: []item{{SKU: "abc-123"}}}
+				err := Validate(&x)
+				assert.NoError(t, err)
+				assert.Equal(t, "ABC-123", x.Items[0].SKU)
+				assert.Equal(t, 1, x.Items[0].Qty)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPlan_CompileError(t *testing.T) {
+	x := struct {
+		I *int `dv8:"val<=abc"`
+	}{}
+	// Not reached
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	i := 5
+	x.I = &i
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "I: strconv.ParseInt")
+}
+
+func TestPlan_UnexportedFields(t *testing.T) {
+	x := struct {
+		S string `dv8:"required"`
+		i int
+		t struct{ J int }
+	}{S: "x"}
+	err := Validate(&x)
+	assert.NoError(t, err)
+}
+
+func TestPlan_Strict(t *testing.T) {
+	ctx := context.Background()
+	strict := Options{Strict: true}
+
+	// Unknown directive
+	x1 := struct {
+		S string `dv8:"requried"`
+	}{S: "x"}
+	err := Validate(&x1)
+	assert.NoError(t, err)
+	err = ValidateOptions(ctx, &x1, strict)
+	var tagErr *TagError
+	if assert.True(t, errors.As(err, &tagErr)) {
+		assert.Equal(t, "S", tagErr.Field)
+		assert.ErrorContains(t, err, "unknown directive 'requried'")
+	}
+
+	// Inapplicable directives
+	x2 := struct {
+		I int            `dv8:"len<=32"`
+		B bool           `dv8:"regexp ^true$"`
+		S string         `dv8:"arrlen>0"`
+		A []*int         `dv8:"arrlen>0,val>0,oneof 1|2"`
+		M map[string]int `dv8:"maplen>0,len>0"`
+	}{}
+	err = ValidateOptions(ctx, &x2, strict)
+	if assert.Error(t, err) {
+		errs, ok := err.(Errors)
+		if assert.True(t, ok) && assert.Len(t, errs, 5) {
+			assert.ErrorContains(t, errs[0], "field 'I'")
+			assert.ErrorContains(t, errs[0], "directive 'len<=32' is not applicable to 'int'")
+			assert.ErrorContains(t, errs[1], "field 'B'")
+			assert.ErrorContains(t, errs[1], "'regexp ^true$' is not applicable to 'bool'")
+			assert.ErrorContains(t, errs[2], "field 'S'")
+			assert.ErrorContains(t, errs[2], "'arrlen>0' is not applicable to 'string'")
+			assert.ErrorContains(t, errs[3], "field 'A'")
+			assert.ErrorContains(t, errs[3], "'oneof 1|2' is not applicable to 'int'")
+			assert.ErrorContains(t, errs[4], "field 'M'")
+			assert.ErrorContains(t, errs[4], "'len>0' is not applicable to 'int'")
+		}
+	}
+
+	// Conflicting directives
+	x3 := struct {
+		S string `dv8:"tolower,toupper"`
+		T string `dv8:"default=a,default=b"`
+	}{}
+	err = ValidateOptions(ctx, &x3, strict)
+	assert.ErrorContains(t, err, "conflicting directives 'tolower' and 'toupper'")
+	assert.ErrorContains(t, err, "multiple defaults")
+
+	// Errors are reported even if the field is not reached
+	type nested struct {
+		I int `dv8:"val<=abc"`
+	}
+	x4 := struct {
+		N *nested
+		A []nested
+	}{}
+	err = ValidateOptions(ctx, &x4, strict)
+	if assert.True(t, errors.As(err, &tagErr)) {
+		assert.Equal(t, "I", tagErr.Field)
+		assert.Equal(t, reflect.TypeOf(nested{}), tagErr.Type)
+		assert.ErrorContains(t, err, "strconv.ParseInt")
+	}
+
+	// Directives pushed down to nested fields
+	type key struct {
+		ID int `dv8:"main"`
+	}
+	type person struct {
+		Name string
+	}
+	x5 := struct {
+		K  key    `dv8:"required,val>0"`
+		P  person `dv8:"default=Unknown,on Name"`
+		K2 key    `dv8:"regexp ^[0-9]+$"`
+		P2 person `dv8:"on Nmae"`
+		P3 person `dv8:"required,len>0"`
+	}{}
+	err = ValidateOptions(ctx, &x5, strict)
+	if assert.Error(t, err) {
+		errs, ok := err.(Errors)
+		if assert.True(t, ok) && assert.Len(t, errs, 3) {
+			assert.ErrorContains(t, errs[0], "field 'K2'")
+			assert.ErrorContains(t, errs[0], "'regexp ^[0-9]+$' is not applicable to 'int'")
+			assert.ErrorContains(t, errs[1], "field 'P2'")
+			assert.ErrorContains(t, errs[1], "field 'Nmae' of directive 'on Nmae' not found")
+			assert.ErrorContains(t, errs[2], "field 'P3'")
+			assert.ErrorContains(t, errs[2], "'len>0' is not applicable")
+		}
+	}
+
+	// Valid tags
+	x6 := struct {
+		K key    `dv8:"required,val>0"`
+		P person `dv8:"default=Unknown,on Name"`
+		S string `dv8:"required,len<=32,regexp '^[a-z]{1,32}$',tolower"`
+		N []int  `dv8:"arrlen<=2,val>=0"`
+	}{
+		K: key{ID: 1},
+		S: "ABC",
+		N: []int{},
+	}
+	err = ValidateOptions(ctx, &x6, strict)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unknown", x6.P.Name)
+	assert.Equal(t, "abc", x6.S)
+}
+
+func TestPlan_Groups(t *testing.T) {
+	ctx := context.Background()
+	type address struct {
+		Zip string `dv8:"required@create,len<=5"`
+	}
+	type user struct {
+		ID      int    `dv8:"val==0@create,required@update|import"`
+		Name    string `dv8:"required@create,default='Anonymous'@import"`
+		Address address
+		Any     any `dv8:"len<=3@create"`
+	}
+	create := Options{Groups: []string{"create"}}
+	update := Options{Groups: []string{"update"}}
+
+	x := user{ID: 5, Name: "Jane", Address: address{Zip: "12345"}}
+	err := ValidateOptions(ctx, &x, create)
+	assert.ErrorContains(t, err, "ID: must equal 0")
+	err = ValidateOptions(ctx, &x, update)
+	assert.NoError(t, err)
+
+	x.ID = 0
+	err = ValidateOptions(ctx, &x, create)
+	assert.NoError(t, err)
+	err = ValidateOptions(ctx, &x, update)
+	assert.ErrorContains(t, err, "ID: non-zero value is required")
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	// Nested structs
+	x.Address.Zip = ""
+	err = ValidateOptions(ctx, &x, create)
+	assert.ErrorContains(t, err, "Address: Zip: value is required")
+	err = ValidateOptions(ctx, &x, update)
+	assert.ErrorContains(t, err, "ID: non-zero value is required")
+	x.ID = 5
+	err = ValidateOptions(ctx, &x, update)
+	assert.NoError(t, err)
+
+	// Interfaces
+	x.ID = 0
+	x.Address.Zip = "12345"
+	x.Any = "abcd"
+	err = ValidateOptions(ctx, &x, create)
+	assert.ErrorContains(t, err, "Any: length")
+	x.ID = 5
+	err = ValidateOptions(ctx, &x, update)
+	assert.NoError(t, err)
+
+	// Normalizations
+	x.Name = ""
+	err = ValidateOptions(ctx, &x, Options{Groups: []string{"import"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Anonymous", x.Name)
+
+	// Conflicts in different groups
+	y := struct {
+		S string `dv8:"default='a'@create,default='b'@update,toupper@create,tolower@update"`
+		T string `dv8:"default='a'@create,default=b"`
+	}{}
+	err = Check(reflect.TypeOf(y))
+	if assert.Error(t, err) {
+		assert.ErrorContains(t, err, "field 'T'")
+		assert.NotContains(t, err.Error(), "field 'S'")
+	}
+}
+
+func TestPlan_GroupsTextArgs(t *testing.T) {
+	ctx := context.Background()
+	x := struct {
+		From  string `dv8:"default=noreply@example"`
+		To    string `dv8:"oneof a@x|b@y"`
+		Count int    `dv8:"default=1@create"`
+	}{
+		To: "b@y",
+	}
+	// Text arguments are read as a whole
+	err := Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "noreply@example", x.From)
+	assert.Equal(t, 0, x.Count)
+
+	err = ValidateOptions(ctx, &x, Options{Groups: []string{"create"}, Strict: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, x.Count)
+
+	x.To = "b"
+	err = ValidateOptions(ctx, &x, Options{Groups: []string{"update"}})
+	assert.ErrorContains(t, err, "To: value must be one of")
+}
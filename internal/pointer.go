@@ -16,18 +16,23 @@ limitations under the License.
 package internal
 
 import (
-	"context"
-	"errors"
 	"reflect"
 )
 
-// validatePointer validates the value of a pointer against the tags.
-func validatePointer(ctx context.Context, refType reflect.Type, refVal reflect.Value, tags []string) (err error) {
+// compilePointer compiles the plan of a pointer and of its target.
+func (c *compiler) compilePointer(p *plan, dirs []directive) error {
+	p.required = hasDirective(dirs, "required")
+	p.elem = c.compile(p.refType.Elem(), without(dirs, nilDirectives))
+	if c.opts.strict {
+		return p.elem.err
+	}
+	return nil
+}
+
+// validatePointer validates the value of a pointer against its plan.
+func validatePointer(w *walk, p *plan, refVal reflect.Value) (err error) {
 	if refVal.IsNil() {
-		if tagsContain(tags, "required") {
-			return errors.New("value is required")
-		}
-		return nil
+		return validateNil(p, refVal)
 	}
-	return validateAny(ctx, refType.Elem(), refVal.Elem(), tags)
+	return validateAny(w, p.elem, refVal.Elem())
 }
@@ -16,7 +16,9 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -53,3 +55,48 @@ func TestPointer_ValidatorOfAnonymous(t *testing.T) {
 	err := Validate(x)
 	assert.ErrorContains(t, err, "too small")
 }
+
+func TestPointer_NilDirectives(t *testing.T) {
+	empty := ""
+	two := "CA"
+	x := struct {
+		NonNil   *string `dv8:"nonnil,len<=2"`
+		Required *string `dv8:"required,len<=2"`
+		Nullable *string `dv8:"nullable,len==2"`
+	}{
+		NonNil:   &empty,
+		Required: &two,
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.NonNil = nil
+	x.Required = &empty
+	x.Nullable = &empty
+	err = ValidateAll(context.Background(), &x)
+	if assert.Len(t, err, 3) {
+		assert.ErrorContains(t, err.(Errors)[0], "NonNil: non-nil value is required")
+		assert.ErrorContains(t, err.(Errors)[1], "Required: value is required")
+		assert.ErrorContains(t, err.(Errors)[2], "Nullable: length must equal 2")
+	}
+
+	x.Required = nil
+	err = ValidateAll(context.Background(), &x)
+	assert.ErrorContains(t, err, "Required: value is required")
+}
+
+func TestPointer_NilConflicts(t *testing.T) {
+	x := struct {
+		S *string `dv8:"nullable,required"`
+		A []int   `dv8:"nullable,nonnil"`
+	}{}
+	err := Check(reflect.TypeOf(x))
+	assert.ErrorContains(t, err, "conflicting directives 'nullable' and 'required'")
+	assert.ErrorContains(t, err, "conflicting directives 'nullable' and 'nonnil'")
+
+	y := struct {
+		S string `dv8:"nonnil"`
+	}{}
+	err = Check(reflect.TypeOf(y))
+	assert.ErrorContains(t, err, "directive 'nonnil' is not applicable")
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+Change is a normalization of a single field made during validation.
+
+Example:
+
+	Change{
+		Path:      Path{{Field: "State"}},
+		Directive: "default",
+		Old:       "",
+		New:       "CA",
+	}
+*/
+type Change struct {
+	Path      Path   // Path to the field from the validated value
+	Directive string // Directive that made the change, e.g. "trim", "toupper", "tolower" or "default"
+	Old       any    // Value before the change
+	New       any    // Value after the change
+}
+
+// String returns the change in the form "Name: toupper: 'rex' -> 'REX'".
+func (c Change) String() string {
+	if len(c.Path) == 0 {
+		return fmt.Sprintf("%s: '%v' -> '%v'", c.Directive, c.Old, c.New)
+	}
+	return fmt.Sprintf("%v: %s: '%v' -> '%v'", c.Path, c.Directive, c.Old, c.New)
+}
+
+// ValidateReport is the same as ValidateOptions but also returns the changes made by the normalizations of the built-in directives,
+// in order of their occurrence.
+// In dry run mode, the changes are those that would have been made.
+// No changes are returned if they are rolled back in atomic mode.
+func ValidateReport(ctx context.Context, data any, opts Options) ([]Change, error) {
+	w := &walk{ctx: ctx, opts: opts, report: true}
+	err := w.validate(data)
+	if err == nil {
+		return w.changes, nil
+	}
+	if opts.Atomic {
+		w.rollback()
+		w.changes = nil
+	}
+	return w.changes, w.finish(err)
+}
+
+// push enters the path segment when reporting changes.
+func (w *walk) push(seg PathSegment) {
+	if w.report {
+		w.path = append(w.path, seg)
+	}
+}
+
+// pop leaves the last path segment when reporting changes.
+func (w *walk) pop() {
+	if w.report {
+		w.path = w.path[:len(w.path)-1]
+	}
+}
+
+// recordChanges records the changes made to the value at the current path when reporting changes.
+func (w *walk) recordChanges(changes []Change) {
+	for _, c := range changes {
+		c.Path = append(Path(nil), w.path...)
+		w.changes = append(w.changes, c)
+	}
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport_Changes(t *testing.T) {
+	type pet struct {
+		Name  string            `dv8:"toupper"`
+		Kind  string            `dv8:"default=Mammal"`
+		Age   int               `dv8:"default=1"`
+		Born  time.Time         `dv8:"default=2020-01-01"`
+		Tags  map[string]string `dv8:"tolower"`
+		Aka   []string
+		Owner *Animal
+	}
+	ctx := context.Background()
+	x := pet{
+		Name:  " Rex",
+		Kind:  "Dog",
+		Tags:  map[string]string{"a": "FOO"},
+		Aka:   []string{"Rexy", " Rexie"},
+		Owner: &Animal{Name: "Jane"},
+	}
+	changes, err := ValidateReport(ctx, &x, Options{})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 7) {
+		assert.Equal(t, "Name: trim: ' Rex' -> 'Rex'", changes[0].String())
+		assert.Equal(t, "Name: toupper: 'Rex' -> 'REX'", changes[1].String())
+		assert.Equal(t, "Age: default: '0' -> '1'", changes[2].String())
+		assert.Equal(t, "Born", changes[3].Path.String())
+		assert.Equal(t, "default", changes[3].Directive)
+		assert.Equal(t, "Tags: [a]: tolower: 'FOO' -> 'foo'", changes[4].String())
+		assert.Equal(t, "Aka: [1]: trim: ' Rexie' -> 'Rexie'", changes[5].String())
+		assert.Equal(t, "Owner: Kind: default: '' -> 'Mammal'", changes[6].String())
+	}
+
+	// Nothing to change the second time around
+	changes, err = ValidateReport(ctx, &x, Options{})
+	assert.NoError(t, err)
+	assert.Len(t, changes, 0)
+
+	// Changes that would have been made
+	x.Name = "rex"
+	changes, err = ValidateReport(ctx, &x, Options{DryRun: true})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "Name: toupper: 'rex' -> 'REX'", changes[0].String())
+	}
+	assert.Equal(t, "rex", x.Name)
+}
+
+func TestReport_On(t *testing.T) {
+	type child struct {
+		Name string
+	}
+	type parent struct {
+		C child `dv8:"on Name,toupper"`
+	}
+	x := parent{C: child{Name: "rex"}}
+	changes, err := ValidateReport(context.Background(), &x, Options{})
+	assert.NoError(t, err)
+	if assert.Len(t, changes, 1) {
+		assert.Equal(t, "C: Name: toupper: 'rex' -> 'REX'", changes[0].String())
+	}
+	assert.Equal(t, "REX", x.C.Name)
+}
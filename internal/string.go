@@ -20,138 +20,171 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
-	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
-// validateString validates the value of a string against the tags.
-func validateString(refVal reflect.Value, tags []string) (err error) {
-	s := refVal.String()
+// stringDirectives are the directives applicable to a string.
+var stringDirectives = []string{"required", "optional", "default", "trim", "notrim", "toupper", "tolower", "len", "val", "regexp", "oneof"}
+
+// compileString compiles the directives that apply to a string.
+// Strings are trimmed by default unless notrim is present, or only if trim is present.
+func compileString(p *plan, dirs []directive, trimByDefault bool) error {
+	var errs Errors
 	// Trim spaces
-	changed := false
-	if !tagsContain(tags, "notrim") {
-		trimmed := strings.TrimSpace(s)
-		if trimmed != s {
-			s = trimmed
-			changed = true
-		}
+	trim := trimByDefault
+	if hasDirective(dirs, "notrim") {
+		trim = false
+	} else if hasDirective(dirs, "trim") {
+		trim = true
+	}
+	if trim {
+		p.normalizers = append(p.normalizers, normalizer{
+			directive: "trim",
+			apply: func(refVal reflect.Value) (reflect.Value, bool) {
+				s := refVal.String()
+				trimmed := strings.TrimSpace(s)
+				if trimmed == s {
+					return refVal, false
+				}
+				return reflect.ValueOf(trimmed).Convert(refVal.Type()), true
+			},
+		})
 	}
 	// Default value and required
 	required := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if t == "toupper" && s != strings.ToUpper(s) {
-			s = strings.ToUpper(s)
-			changed = true
-		} else if t == "tolower" && s != strings.ToLower(s) {
-			s = strings.ToLower(s)
-			changed = true
-		} else if s == "" && strings.HasPrefix(t, "default=") {
-			def := t[len("default="):]
-			if def != s {
-				s = def
-				changed = true
+		case "toupper":
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					s := refVal.String()
+					upper := strings.ToUpper(s)
+					if upper == s {
+						return refVal, false
+					}
+					return reflect.ValueOf(upper).Convert(refVal.Type()), true
+				},
+			})
+		case "tolower":
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					s := refVal.String()
+					lower := strings.ToLower(s)
+					if lower == s {
+						return refVal, false
+					}
+					return reflect.ValueOf(lower).Convert(refVal.Type()), true
+				},
+			})
+		case "default":
+			if d.arg == "" {
+				continue
 			}
+			def := reflect.ValueOf(d.arg).Convert(p.refType)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.String() != "" {
+						return refVal, false
+					}
+					return def, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetString(s)
-	}
-	if s == "" && required {
-		return errors.New("value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("value is required", reflect.Value.IsZero))
 	}
 	// Other constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "len") && len(t) > 4 {
+	for _, d := range dirs {
+		switch d.name {
+		case "len":
 			// Example: len<8
-			operator := t[3:4]
-			var l int
-			if t[4] == '=' {
-				operator += "="
-				l, err = strconv.Atoi(t[5:])
-			} else {
-				l, err = strconv.Atoi(t[4:])
-			}
-			if err != nil {
-				return err
-			}
-			strLen := len([]rune(s))
-			switch {
-			case operator == "<=" && strLen > l:
-				err = fmt.Errorf("length must be less than or equal to %d", l)
-			case operator == "<" && strLen >= l:
-				err = fmt.Errorf("length must be less than %d", l)
-			case operator == ">=" && strLen < l:
-				err = fmt.Errorf("length must be greater than or equal to %d", l)
-			case operator == ">" && strLen <= l:
-				err = fmt.Errorf("length must be greater than %d", l)
-			case operator == "!=" && strLen == l:
-				err = fmt.Errorf("length must not equal %d", l)
-			case operator == "==" && strLen != l:
-				err = fmt.Errorf("length must equal %d", l)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
+			cons, err := compileLen(d, func(refVal reflect.Value) int {
+				return utf8.RuneCountInString(refVal.String())
+			})
 			if err != nil {
-				return err
+				errs = append(errs, err)
+				continue
 			}
-		} else if strings.HasPrefix(t, "val") && len(t) > 4 {
+			p.constraints = append(p.constraints, cons)
+		case "val":
 			// Example: val<M
-			operator := t[3:4]
-			var v string
-			if t[4] == '=' {
-				operator += "="
-				v = t[5:]
-			} else {
-				v = t[4:]
-			}
-			if err != nil {
-				return err
+			operator := d.op
+			v := d.arg
+			switch operator {
+			case "<=", "<", ">=", ">", "!=", "==":
+			default:
+				errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+				continue
 			}
-			switch {
-			case operator == "<=" && s > v:
-				err = fmt.Errorf("must be less than or equal to '%s'", v)
-			case operator == "<" && s >= v:
-				err = fmt.Errorf("must be less than '%s'", v)
-			case operator == ">=" && s < v:
-				err = fmt.Errorf("must be greater than or equal to '%s'", v)
-			case operator == ">" && s <= v:
-				err = fmt.Errorf("must be greater than '%s'", v)
-			case operator == "!=" && s == v:
-				err = fmt.Errorf("must not equal '%s'", v)
-			case operator == "==" && s != v:
-				err = fmt.Errorf("must equal '%s'", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
+			p.constraints = append(p.constraints, constraint{
+				directive: d.name,
+				operator:  operator,
+				param:     d.arg,
+				check: func(refVal reflect.Value) error {
+					s := refVal.String()
+					switch {
+					case operator == "<=" && s > v:
+						return fmt.Errorf("must be less than or equal to '%s'", v)
+					case operator == "<" && s >= v:
+						return fmt.Errorf("must be less than '%s'", v)
+					case operator == ">=" && s < v:
+						return fmt.Errorf("must be greater than or equal to '%s'", v)
+					case operator == ">" && s <= v:
+						return fmt.Errorf("must be greater than '%s'", v)
+					case operator == "!=" && s == v:
+						return fmt.Errorf("must not equal '%s'", v)
+					case operator == "==" && s != v:
+						return fmt.Errorf("must equal '%s'", v)
+					}
+					return nil
+				},
+			})
+		case "regexp":
+			if d.arg == "" {
+				continue
 			}
+			re, err := regexp.Compile(d.arg)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-		} else if strings.HasPrefix(t, "regexp ") && len(t) > 7 {
-			re, err := regexp.Compile(t[7:])
-			if err != nil {
-				return err
-			}
-			if !re.Match([]byte(s)) {
-				return errors.New("value doesn't match required pattern")
-			}
-		} else if strings.HasPrefix(t, "oneof ") && len(t) > 6 {
-			validVals := strings.Split(t[6:], "|")
-			found := false
-			for _, v := range validVals {
-				if s == v {
-					found = true
-					break
-				}
+			p.constraints = append(p.constraints, constraint{
+				directive: d.name,
+				param:     d.arg,
+				check: func(refVal reflect.Value) error {
+					if !re.MatchString(refVal.String()) {
+						return errors.New("value doesn't match required pattern")
+					}
+					return nil
+				},
+			})
+		case "oneof":
+			if d.arg == "" {
+				continue
 			}
-			if !found {
-				return errors.New("value must be one of " + t[6:])
+			validVals := map[string]bool{}
+			for _, v := range splitArg(d.arg, '|') {
+				validVals[v] = true
 			}
+			msg := "value must be one of " + d.arg
+			p.constraints = append(p.constraints, constraint{
+				directive: d.name,
+				param:     d.arg,
+				check: func(refVal reflect.Value) error {
+					if !validVals[refVal.String()] {
+						return errors.New(msg)
+					}
+					return nil
+				},
+			})
 		}
 	}
-	return nil
+	return errs.join()
 }
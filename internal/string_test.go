@@ -16,6 +16,7 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -196,6 +197,31 @@ func TestString_NoTrim(t *testing.T) {
 	assert.Equal(t, "  Foo  ", x.S)
 }
 
+func TestString_TrimByDefault(t *testing.T) {
+	x := struct {
+		Secret string `dv8:"required"`
+		User   string `dv8:"required,trim"`
+	}{
+		Secret: " s3cret ",
+		User:   " jane ",
+	}
+	err := ValidateOptions(context.Background(), &x, Options{NoTrim: true})
+	assert.NoError(t, err)
+	assert.Equal(t, " s3cret ", x.Secret)
+	assert.Equal(t, "jane", x.User)
+
+	err = Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret", x.Secret)
+
+	// Trim and notrim conflict
+	y := struct {
+		S string `dv8:"trim,notrim"`
+	}{}
+	err = ValidateOptions(context.Background(), &y, Options{Strict: true})
+	assert.ErrorContains(t, err, "conflicting directives 'trim' and 'notrim'")
+}
+
 func TestString_Val(t *testing.T) {
 	gte := struct {
 		S string `dv8:"val>=2"`
@@ -359,3 +385,35 @@ func TestString_OneOf(t *testing.T) {
 	err = Validate(&y)
 	assert.NoError(t, err)
 }
+
+func TestString_Optional(t *testing.T) {
+	x := struct {
+		State string `dv8:"optional,toupper,len==2"`
+	}{
+		State: "  ",
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "", x.State)
+
+	x.State = " ca "
+	err = Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "CA", x.State)
+
+	x.State = "Cal"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "length must equal 2")
+
+	y := struct {
+		State string `dv8:"optional,default=CAL,len==2"`
+	}{}
+	err = Validate(&y)
+	assert.ErrorContains(t, err, "length must equal 2")
+
+	z := struct {
+		State string `dv8:"optional,required"`
+	}{}
+	err = ValidateOptions(context.Background(), &z, Options{Strict: true})
+	assert.ErrorContains(t, err, "conflicting directives 'optional' and 'required'")
+}
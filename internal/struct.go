@@ -16,68 +16,262 @@ limitations under the License.
 package internal
 
 import (
-	"context"
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
 )
 
-// validateStruct takes in a data struct and validates each of its fields given their dv8 field tags.
-func validateStruct(ctx context.Context, refType reflect.Type, refVal reflect.Value, structTags []string) (err error) {
-	if tagsContain(structTags, "required") {
-		zero := reflect.Zero(refType)
-		if reflect.DeepEqual(zero.Interface(), refVal.Interface()) {
-			return errors.New("value is required")
+// compileStruct compiles the plan of a struct and of each of its fields given their dv8 field tags.
+// It returns the directives applicable to the struct itself, or nil if they are pushed down to nested fields.
+func (c *compiler) compileStruct(p *plan, structDirs []directive) (applicable []string, err error) {
+	refType := p.refType
+	var errs Errors
+	// Directives pushed down to nested fields by on and main
+	// Custom directives apply to the struct itself
+	// Expressions apply to the struct itself
+	var pushed []directive
+	for _, d := range structDirs {
+		if d.name != "on" && d.name != "expr" && customDirective(d.name) == nil {
+			pushed = append(pushed, d)
 		}
 	}
-	// On runs the validation on a nested field
-	for _, t := range structTags {
-		if strings.HasPrefix(t, "on ") {
-			fld, ok := refType.FieldByName(t[3:])
-			if ok {
-				rt := fld.Type
-				rv := refVal.FieldByName(t[3:])
-				err = validateAny(ctx, rt, rv, structTags)
-				if err != nil {
-					return err
+	for _, d := range structDirs {
+		switch d.name {
+		case "required":
+			p.required = true
+		case "expr":
+			x, err := compileExpr(refType, d)
+			if err != nil {
+				errs = appendUnique(errs, err)
+				continue
+			}
+			p.exprs = append(p.exprs, x)
+		case "on":
+			// On runs the validation on a nested field
+			fld, ok := refType.FieldByName(d.arg)
+			if !ok {
+				if c.opts.strict {
+					errs = appendUnique(errs, fmt.Errorf("field '%s' of directive '%s' not found in '%v'", d.arg, d.raw, refType))
 				}
+				continue
+			}
+			on := &fieldPlan{
+				index: fld.Index,
+				seg:   PathSegment{Field: fld.Name, Tag: fld.Tag, Embedded: embeddedStruct(fld)},
+				plan:  c.compile(fld.Type, pushed),
+			}
+			p.on = append(p.on, on)
+			if c.opts.strict {
+				errs = appendUnique(errs, on.plan.err)
 			}
 		}
 	}
-	// Iterate over fields
+	hasMain := false
 	for i := 0; i < refType.NumField(); i++ {
 		fld := refType.Field(i)
-		tagVal := fld.Tag.Get("dv8")
+		tagVal := c.opts.tagOf(fld)
 		if tagVal == "-" {
 			continue
 		}
-		fldTags := strings.Split(tagVal, ",")
-		if tagsContain(fldTags, "-") {
+		fldDirs, err := parseTag(tagVal)
+		if err == nil {
+			fldDirs, err = expandAliases(fldDirs)
+		}
+		fldDirs = c.opts.inGroups(fldDirs)
+		if fld.Name == "_" && err == nil {
+			// Expressions of the struct may be set on a blank marker field
+			var fldErrs Errors
+			for _, d := range fldDirs {
+				if d.name != "expr" {
+					continue
+				}
+				x, err := compileExpr(refType, d)
+				if err != nil {
+					fldErrs = append(fldErrs, err)
+					continue
+				}
+				p.exprs = append(p.exprs, x)
+			}
+			if c.opts.strict {
+				fldErrs = appendUnique(fldErrs, checkDirectives(fld.Type, fldDirs, []string{"expr"}))
+			}
+			if len(fldErrs) > 0 {
+				if c.opts.strict {
+					errs = appendUnique(errs, tagErrors(refType, fld.Name, fldErrs))
+				} else {
+					errs = appendUnique(errs, fldErrs)
+				}
+			}
+			continue
+		}
+		if err != nil {
+			p.fields = append(p.fields, &fieldPlan{
+				index: fld.Index,
+				seg:   PathSegment{Field: fld.Name, Tag: fld.Tag, Embedded: embeddedStruct(fld)},
+				plan:  &plan{refType: fld.Type, err: err},
+			})
+			if c.opts.strict {
+				errs = appendUnique(errs, tagErrors(refType, fld.Name, err))
+			}
+			continue
+		}
+		if hasDirective(fldDirs, "-") {
 			continue
 		}
-		rt := fld.Type
-		rv := refVal.Field(i)
+		conds, fldDirs, condErr := compileConditions(refType, fld.Type, fldDirs)
+		fp := &fieldPlan{
+			index: fld.Index,
+			seg:   PathSegment{Field: fld.Name, Tag: fld.Tag, Embedded: embeddedStruct(fld)},
+			plan:  c.compile(fld.Type, fldDirs),
+			conds: conds,
+		}
+		if condErr != nil {
+			fp.plan = &plan{refType: fld.Type, err: appendUnique(appendUnique(nil, condErr), fp.plan.err).join()}
+		}
+		if c.opts.strict && fp.plan.err != nil {
+			errs = appendUnique(errs, tagErrors(refType, fld.Name, fp.plan.err))
+		}
 		// Main fields run validations of the parent struct too
-		if tagsContain(fldTags, "main") {
-			err = validateAny(ctx, rt, rv, structTags)
+		if hasDirective(fldDirs, "main") {
+			hasMain = true
+			fp.main = c.compile(fld.Type, pushed)
+			if c.opts.strict {
+				// Attributed to the field of the parent struct that holds the directives
+				errs = appendUnique(errs, fp.main.err)
+			}
+		}
+		p.fields = append(p.fields, fp)
+	}
+	if len(p.on) == 0 && !hasMain {
+		applicable = []string{"required", "on", "expr"}
+	}
+	return applicable, errs.orNil()
+}
+
+// tagErrors attributes the errors to the field of the struct, unless they are already attributed to the field of a nested struct.
+func tagErrors(refType reflect.Type, field string, err error) Errors {
+	var errs Errors
+	for _, e := range appendUnique(nil, err) {
+		if _, ok := e.(*TagError); !ok {
+			e = &TagError{
+				Type:  refType,
+				Field: field,
+				Err:   e,
+			}
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// validateStruct takes in a data struct and validates each of its fields given their dv8 field tags.
+func validateStruct(w *walk, p *plan, refVal reflect.Value) (err error) {
+	if p.required && refVal.IsZero() {
+		return &FieldError{
+			Directive: "required",
+			Value:     valueOf(refVal),
+			Err:       errors.New("value is required"),
+		}
+	}
+	var errs Errors
+	sel := w.sel
+	defer func() { w.sel = sel }()
+	// The directives of the struct apply to the nested field, if selected
+	for _, on := range p.on {
+		w.sel = nil
+		if !sel.whole() {
+			w.sel = sel.field(on.seg, w.opts.PathTagKey)
+			if w.sel == nil {
+				continue
+			}
+		}
+		w.push(on.seg)
+		err = validateAny(w, on.plan, refVal.FieldByIndex(on.index))
+		w.pop()
+		if err != nil {
+			if !w.opts.All {
+				return err
+			}
+			errs = errs.collect(err)
+		}
+	}
+	// Iterate over fields
+	for _, fld := range p.fields {
+		if !sel.whole() {
+			// Only the selected fields are validated
+			w.sel = sel.field(fld.seg, w.opts.PathTagKey)
+			if w.sel == nil {
+				continue
+			}
+		}
+		rv := refVal.FieldByIndex(fld.index)
+		w.push(fld.seg)
+		if fld.main != nil {
+			err = validateAny(w, fld.main, rv)
 			if err != nil {
-				return fmt.Errorf("%s: %w", fld.Name, err)
+				w.pop()
+				err = atPath(fld.seg, err)
+				if !w.opts.All {
+					return err
+				}
+				errs = errs.collect(err)
+				continue
 			}
 		}
-		err = validateAny(ctx, rt, rv, fldTags)
+		err = validateAny(w, fld.plan, rv)
+		w.pop()
 		if err != nil {
-			return fmt.Errorf("%s: %w", fld.Name, err)
+			err = atPath(fld.seg, err)
+			if !w.opts.All {
+				return err
+			}
+			errs = errs.collect(err)
 		}
 	}
-	return nil
+	// Directives that reference sibling fields are evaluated once all fields are normalized
+	structVal := refVal
+	if w.opts.DryRun && !w.normalizing && (p.hasConds() || len(p.exprs) > 0) {
+		// The normalized values were not set, so the directives and expressions are evaluated against a normalized copy
+		structVal = normalizedCopy(w, p, refVal)
+	}
+	for _, fld := range p.fields {
+		if len(fld.conds) == 0 || (!sel.whole() && sel.field(fld.seg, w.opts.PathTagKey) == nil) {
+			continue
+		}
+		err = validateConditions(fld, structVal)
+		if err != nil {
+			err = atPath(fld.seg, err)
+			if !w.opts.All {
+				return err
+			}
+			errs = errs.collect(err)
+		}
+	}
+	// Expressions are evaluated only if all fields are valid
+	if len(errs) > 0 || !sel.whole() {
+		return errs.orNil()
+	}
+	return validateExprs(p, structVal)
 }
 
-func tagsContain(tags []string, val string) bool {
-	for _, t := range tags {
-		if t == val {
+// hasConds returns true if any of the fields of the struct has directives that reference its sibling fields.
+func (p *plan) hasConds() bool {
+	for _, fld := range p.fields {
+		if len(fld.conds) > 0 {
 			return true
 		}
 	}
 	return false
 }
+
+// embeddedStruct returns true if the field is an embedded struct, or a pointer to one.
+func embeddedStruct(fld reflect.StructField) bool {
+	if !fld.Anonymous {
+		return false
+	}
+	refType := fld.Type
+	if refType.Kind() == reflect.Pointer {
+		refType = refType.Elem()
+	}
+	return refType.Kind() == reflect.Struct
+}
@@ -16,6 +16,7 @@ limitations under the License.
 package internal
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
@@ -93,6 +94,34 @@ func TestStruct_Nesting(t *testing.T) {
 	assert.ErrorContains(t, err, "required")
 }
 
+func TestStruct_TagKeys(t *testing.T) {
+	x := struct {
+		Name  string `validate:"required"`
+		Email string `dv8:"tolower" validate:"required"`
+		Skip  string `dv8:"-" validate:"required"`
+	}{
+		Email: "JANE@EXAMPLE.COM",
+	}
+	opts := Options{TagKeys: []string{"dv8", "validate"}}
+	err := ValidateOptions(context.Background(), &x, opts)
+	assert.ErrorContains(t, err, "Name: value is required")
+
+	x.Name = "Jane"
+	err = ValidateOptions(context.Background(), &x, opts)
+	assert.NoError(t, err)
+	assert.Equal(t, "jane@example.com", x.Email)
+
+	// Only the validate tag
+	x.Email = ""
+	err = ValidateOptions(context.Background(), &x, Options{TagKeys: []string{"validate"}})
+	assert.ErrorContains(t, err, "Email: value is required")
+
+	// The dv8 tag by default
+	x.Name = ""
+	err = Validate(&x)
+	assert.NoError(t, err)
+}
+
 func TestStruct_On1(t *testing.T) {
 	type child struct {
 		I int
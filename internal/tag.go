@@ -0,0 +1,387 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// directive is a single directive of a tag, e.g. "len<=32", "default=CA" or "regexp ^[0-9]+$".
+type directive struct {
+	raw    string   // Text of the directive as it appears in the tag
+	name   string   // Name of the directive, e.g. "len"
+	op     string   // Operator of the directive, e.g. "<=", or "=" for default
+	arg    string   // Argument of the directive after removing quotes and escapes, e.g. "32"
+	groups []string // Validation groups the directive is limited to, e.g. "create", or empty for all groups
+
+	alts [][]directive // Alternatives of a group, or the negated directive, e.g. "(len==0 | len==5)" or "!oneof admin|root"
+}
+
+/*
+parseTag breaks a tag into its directives.
+
+Directives are separated by commas and take the form of a name,
+optionally followed by either an operator or a space, and an argument.
+An argument that contains commas must either escape them with a backslash or be enclosed in single quotes.
+A single quote inside a quoted argument is escaped with a backslash.
+Other backslashes are retained as they are, so that regular expressions need not be escaped twice.
+A directive may be limited to validation groups with a suffix of @ followed by the names of the groups separated by a |.
+An unquoted argument is separated from its groups only if it is a number, a bool, a time, a duration or the name of a field,
+which cannot contain an @. Any other argument, such as that of default, oneof or regexp, is read as a whole,
+and must be enclosed in single quotes to be limited to groups.
+A directive prefixed with ! is negated.
+Alternatives are enclosed in parentheses and separated by a | with a space on either side.
+Each alternative is itself a tag whose directives must all be satisfied.
+Groups may limit the alternatives as a whole, but not the directives inside them.
+
+Example:
+
+	required,len<=32,default=CA
+	regexp '^[a-z]{2,5}$'
+	regexp ^[a-z]{2\,5}$
+	oneof 'Smith, John|Doe, Jane'
+	required@create,val==0@create|import
+	default=noreply@example,default='admin@localhost'@import
+	!oneof admin|root
+	(len==0 | regexp ^[0-9]{5}$)
+*/
+func parseTag(tag string) ([]directive, error) {
+	var dirs []directive
+	i := 0
+	for i < len(tag) {
+		if tag[i] == ',' || tag[i] == ' ' {
+			i++
+			continue
+		}
+		d, next, err := scanDirective(tag, i)
+		if err != nil {
+			return nil, err
+		}
+		dirs = append(dirs, d)
+		i = next
+	}
+	return dirs, nil
+}
+
+// scanDirective scans a single directive starting at the given position of the tag.
+// It returns the directive and the position immediately following it.
+func scanDirective(tag string, i int) (d directive, next int, err error) {
+	start := i
+	if tag[i] == '-' && (i+1 == len(tag) || tag[i+1] == ',') {
+		return directive{raw: "-", name: "-"}, i + 1, nil
+	}
+	if tag[i] == '!' {
+		// Example: !oneof admin|root
+		if i+1 == len(tag) || tag[i+1] == ',' || tag[i+1] == ' ' {
+			return d, i, fmt.Errorf("expected directive after '!' at column %d of tag '%s'", i+1, tag)
+		}
+		negated, next, err := scanDirective(tag, i+1)
+		if err != nil {
+			return d, next, err
+		}
+		// The groups apply to the negation as a whole
+		groups := negated.groups
+		negated.groups = nil
+		d = directive{
+			raw:    "!" + negated.raw,
+			name:   "!",
+			groups: groups,
+			alts:   [][]directive{{negated}},
+		}
+		return d, next, nil
+	}
+	if tag[i] == '(' {
+		// Example: (len==0 | regexp ^[0-9]{5}$)
+		return scanAlternatives(tag, i)
+	}
+	// Name, or an alias prefixed with @
+	if tag[i] == '@' {
+		i++
+	}
+	for i < len(tag) && isNameChar(tag[i]) {
+		i++
+	}
+	d.name = tag[start:i]
+	if d.name == "" || d.name == "@" {
+		return d, i, fmt.Errorf("expected directive name at column %d of tag '%s'", i+1, tag)
+	}
+	// Operator
+	switch {
+	case i == len(tag) || tag[i] == ',':
+	case tag[i] == '@':
+		// Example: required@create
+		at := i
+		d.groups, i, err = scanGroups(tag, i)
+		if err != nil {
+			return d, i, err
+		}
+		d.raw = tag[start:at]
+		return d, i, nil
+	case tag[i] == ' ':
+		// Example: regexp ^[0-9]+$
+		i++
+	case d.name == "default" && tag[i] == '=':
+		// Example: default=CA
+		d.op = "="
+		i++
+	default:
+		// Example: len<=32
+		d.op = tag[i : i+1]
+		if i+1 < len(tag) && tag[i+1] == '=' {
+			d.op = tag[i : i+2]
+		}
+		i += len(d.op)
+	}
+	// Argument
+	var arg strings.Builder
+	suffix := 0 // Length of the groups suffix
+	if i < len(tag) && tag[i] == '\'' {
+		// Example: regexp '^[a-z]{2,5}$'
+		quote := i
+		i++
+		for {
+			if i == len(tag) {
+				return d, i, fmt.Errorf("unterminated quote at column %d of tag '%s'", quote+1, tag)
+			}
+			if tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == '\'' {
+				arg.WriteByte('\'')
+				i += 2
+				continue
+			}
+			if tag[i] == '\'' {
+				i++
+				break
+			}
+			arg.WriteByte(tag[i])
+			i++
+		}
+		if i < len(tag) && tag[i] == '@' {
+			// Example: default='admin@localhost'@create
+			at := i
+			d.groups, i, err = scanGroups(tag, i)
+			if err != nil {
+				return d, i, err
+			}
+			suffix = i - at
+		}
+		if i < len(tag) && tag[i] != ',' {
+			return d, i, fmt.Errorf("unexpected '%c' after closing quote at column %d of tag '%s'", tag[i], i+1, tag)
+		}
+		d.arg = arg.String()
+	} else {
+		// Example: regexp ^[a-z]{2\,5}$
+		for i < len(tag) && tag[i] != ',' {
+			if tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',' {
+				arg.WriteByte(',')
+				i += 2
+				continue
+			}
+			arg.WriteByte(tag[i])
+			i++
+		}
+		// Example: val==0@create
+		d.arg = arg.String()
+		if at := strings.LastIndexByte(d.arg, '@'); at >= 0 && plainArg(d.name, d.arg[:at]) {
+			// Example: default=noreply@example
+			if groups := parseGroups(d.arg[at:]); groups != nil {
+				d.groups = groups
+				suffix = len(d.arg) - at
+				d.arg = d.arg[:at]
+			}
+		}
+	}
+	// The raw text excludes the groups
+	d.raw = tag[start : i-suffix]
+	return d, i, nil
+}
+
+// scanAlternatives scans a group of alternatives enclosed in parentheses starting at the given position of the tag.
+// It returns the group and the position immediately following it.
+func scanAlternatives(tag string, i int) (d directive, next int, err error) {
+	start := i
+	depth := 0
+	quoted := false
+	var alts []string
+	from := i + 1
+	for {
+		if i == len(tag) {
+			return d, i, fmt.Errorf("unterminated parenthesis at column %d of tag '%s'", start+1, tag)
+		}
+		c := tag[i]
+		switch {
+		case c == '\\' && i+1 < len(tag) && tag[i+1] == '\'':
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '|' && depth == 1 && (tag[i-1] == ' ' || i+1 < len(tag) && tag[i+1] == ' '):
+			// Example: len==0 | len==5
+			alts = append(alts, tag[from:i])
+			from = i + 1
+		}
+		i++
+		if depth == 0 {
+			break
+		}
+	}
+	alts = append(alts, tag[from:i-1])
+	d.raw = tag[start:i]
+	d.name = "("
+	for _, alt := range alts {
+		dirs, err := parseTag(strings.TrimSpace(alt))
+		if err != nil {
+			return d, i, err
+		}
+		if len(dirs) == 0 {
+			return d, i, fmt.Errorf("empty alternative in '%s' of tag '%s'", d.raw, tag)
+		}
+		d.alts = append(d.alts, dirs)
+	}
+	if g, ok := groupedDirective(d); ok {
+		// Groups limit the alternatives as a whole, e.g. (len==0 | len==5)@create
+		return d, i, fmt.Errorf("directive '%s' inside '%s' of tag '%s' cannot be limited to groups", g.raw, d.raw, tag)
+	}
+	if i < len(tag) && tag[i] == '@' {
+		// Example: (len==0 | len==5)@create
+		d.groups, i, err = scanGroups(tag, i)
+		if err != nil {
+			return d, i, err
+		}
+	}
+	if i < len(tag) && tag[i] != ',' {
+		return d, i, fmt.Errorf("unexpected '%c' after closing parenthesis at column %d of tag '%s'", tag[i], i+1, tag)
+	}
+	return d, i, nil
+}
+
+// groupedDirective returns the first directive inside the composite that is limited to groups, if any.
+// Only the composite as a whole may be limited to groups.
+func groupedDirective(d directive) (directive, bool) {
+	for _, alt := range d.alts {
+		for _, a := range alt {
+			if len(a.groups) > 0 {
+				return a, true
+			}
+		}
+	}
+	return directive{}, false
+}
+
+// scanGroups scans the groups suffix starting at the @ at the given position of the tag, up to the next comma.
+// It returns the names of the groups and the position immediately following the suffix.
+func scanGroups(tag string, i int) (groups []string, next int, err error) {
+	at := i
+	for i < len(tag) && tag[i] != ',' {
+		i++
+	}
+	groups = parseGroups(tag[at:i])
+	if groups == nil {
+		return nil, i, fmt.Errorf("invalid groups at column %d of tag '%s'", at+1, tag)
+	}
+	return groups, i, nil
+}
+
+// parseGroups parses a suffix such as "@create|import" into the names of its groups.
+// It returns nil if the suffix is not valid.
+func parseGroups(suffix string) []string {
+	if len(suffix) < 2 || suffix[0] != '@' {
+		return nil
+	}
+	groups := strings.Split(suffix[1:], "|")
+	for _, g := range groups {
+		if g == "" {
+			return nil
+		}
+		for i := 0; i < len(g); i++ {
+			if !isNameChar(g[i]) {
+				return nil
+			}
+		}
+	}
+	return groups
+}
+
+/*
+plainArg returns true if the argument of the directive is a number, a bool, a time, a duration or the name of a field.
+Such arguments cannot contain an @, so an @ that follows them unquoted starts the groups of the directive.
+
+Example:
+
+	len<=32@create
+	val==0@create
+	default=1h@import
+	gtfield=Start@create
+*/
+func plainArg(name string, arg string) bool {
+	if arg == "" {
+		return false
+	}
+	switch name {
+	case "len", "arrlen", "maplen":
+		_, err := strconv.Atoi(arg)
+		return err == nil
+	case "val", "default":
+		if name == "val" && isFieldComparison(directive{name: name, arg: arg}) {
+			return true
+		}
+		if _, err := strconv.ParseFloat(arg, 64); err == nil {
+			return true
+		}
+		if _, err := strconv.ParseBool(arg); err == nil {
+			return true
+		}
+		if _, err := time.ParseDuration(arg); err == nil {
+			return true
+		}
+		_, err := parseTime(arg)
+		return err == nil
+	case "on":
+		return true
+	}
+	_, ok := fieldComparisons[name]
+	return ok
+}
+
+// isNameChar returns true if the character can be part of the name of a directive.
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_'
+}
+
+// splitArg splits the argument of a directive on a separator that is not escaped with a backslash.
+func splitArg(arg string, sep byte) []string {
+	var parts []string
+	var part strings.Builder
+	for i := 0; i < len(arg); i++ {
+		switch {
+		case arg[i] == '\\' && i+1 < len(arg) && arg[i+1] == sep:
+			part.WriteByte(sep)
+			i++
+		case arg[i] == sep:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteByte(arg[i])
+		}
+	}
+	return append(parts, part.String())
+}
@@ -0,0 +1,177 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTag_Parse(t *testing.T) {
+	testCases := []struct {
+		tag  string
+		dirs []directive
+	}{
+		{"", nil},
+		{"required", []directive{{raw: "required", name: "required"}}},
+		{"len<=32", []directive{{raw: "len<=32", name: "len", op: "<=", arg: "32"}}},
+		{"val>5", []directive{{raw: "val>5", name: "val", op: ">", arg: "5"}}},
+		{"val==", []directive{{raw: "val==", name: "val", op: "=="}}},
+		{"default=CA", []directive{{raw: "default=CA", name: "default", op: "=", arg: "CA"}}},
+		{"default==", []directive{{raw: "default==", name: "default", op: "=", arg: "="}}},
+		{"regexp ^[a-z]+$", []directive{{raw: "regexp ^[a-z]+$", name: "regexp", arg: "^[a-z]+$"}}},
+		{"regexp ^\\d+$", []directive{{raw: "regexp ^\\d+$", name: "regexp", arg: "^\\d+$"}}},
+		{"on ID", []directive{{raw: "on ID", name: "on", arg: "ID"}}},
+		{"-", []directive{{raw: "-", name: "-"}}},
+		{"required, len>0,,tolower", []directive{
+			{raw: "required", name: "required"},
+			{raw: "len>0", name: "len", op: ">", arg: "0"},
+			{raw: "tolower", name: "tolower"},
+		}},
+		{"regexp '^[a-z]{2,5}$',required", []directive{
+			{raw: "regexp '^[a-z]{2,5}$'", name: "regexp", arg: "^[a-z]{2,5}$"},
+			{raw: "required", name: "required"},
+		}},
+		{"regexp ^[a-z]{2\\,5}$,required", []directive{
+			{raw: "regexp ^[a-z]{2\\,5}$", name: "regexp", arg: "^[a-z]{2,5}$"},
+			{raw: "required", name: "required"},
+		}},
+		{"default='Smith, John',oneof 'Smith, John|Doe, Jane'", []directive{
+			{raw: "default='Smith, John'", name: "default", op: "=", arg: "Smith, John"},
+			{raw: "oneof 'Smith, John|Doe, Jane'", name: "oneof", arg: "Smith, John|Doe, Jane"},
+		}},
+		{"val=='it\\'s'", []directive{{raw: "val=='it\\'s'", name: "val", op: "==", arg: "it's"}}},
+		{"default=it's", []directive{{raw: "default=it's", name: "default", op: "=", arg: "it's"}}},
+		{"default=''", []directive{{raw: "default=''", name: "default", op: "="}}},
+		{"required@create", []directive{{raw: "required", name: "required", groups: []string{"create"}}}},
+		{"val==0@create|import", []directive{{raw: "val==0", name: "val", op: "==", arg: "0", groups: []string{"create", "import"}}}},
+		{"val=='0'@create|import", []directive{{raw: "val=='0'", name: "val", op: "==", arg: "0", groups: []string{"create", "import"}}}},
+		{"len<=3@create", []directive{{raw: "len<=3", name: "len", op: "<=", arg: "3", groups: []string{"create"}}}},
+		{"default=1h30m@import", []directive{{raw: "default=1h30m", name: "default", op: "=", arg: "1h30m", groups: []string{"import"}}}},
+		{"val>=2020-01-01@create", []directive{{raw: "val>=2020-01-01", name: "val", op: ">=", arg: "2020-01-01", groups: []string{"create"}}}},
+		{"val>=Field(Start)@create", []directive{{raw: "val>=Field(Start)", name: "val", op: ">=", arg: "Field(Start)", groups: []string{"create"}}}},
+		{"gtfield=Start@create", []directive{{raw: "gtfield=Start", name: "gtfield", op: "=", arg: "Start", groups: []string{"create"}}}},
+		{"default=noreply@example", []directive{{raw: "default=noreply@example", name: "default", op: "=", arg: "noreply@example"}}},
+		{"val==admin@localhost", []directive{{raw: "val==admin@localhost", name: "val", op: "==", arg: "admin@localhost"}}},
+		{"oneof a@x|b@y", []directive{{raw: "oneof a@x|b@y", name: "oneof", arg: "a@x|b@y"}}},
+		{"oneof a@b|c@d.com", []directive{{raw: "oneof a@b|c@d.com", name: "oneof", arg: "a@b|c@d.com"}}},
+		{"default='a@b'@create", []directive{{raw: "default='a@b'", name: "default", op: "=", arg: "a@b", groups: []string{"create"}}}},
+		{"regexp ^.+@.+$", []directive{{raw: "regexp ^.+@.+$", name: "regexp", arg: "^.+@.+$"}}},
+		{"@zip@create", []directive{{raw: "@zip", name: "@zip", groups: []string{"create"}}}},
+		{"!oneof admin|root", []directive{{raw: "!oneof admin|root", name: "!", alts: [][]directive{
+			{{raw: "oneof admin|root", name: "oneof", arg: "admin|root"}},
+		}}}},
+		{"!required@create", []directive{{raw: "!required", name: "!", groups: []string{"create"}, alts: [][]directive{
+			{{raw: "required", name: "required"}},
+		}}}},
+		{"(len==0 | regexp ^[0-9]{5}$),required", []directive{
+			{raw: "(len==0 | regexp ^[0-9]{5}$)", name: "(", alts: [][]directive{
+				{{raw: "len==0", name: "len", op: "==", arg: "0"}},
+				{{raw: "regexp ^[0-9]{5}$", name: "regexp", arg: "^[0-9]{5}$"}},
+			}},
+			{raw: "required", name: "required"},
+		}},
+		{"(oneof a|b | regexp '^(x|y)$',len>0)@create", []directive{
+			{raw: "(oneof a|b | regexp '^(x|y)$',len>0)", name: "(", groups: []string{"create"}, alts: [][]directive{
+				{{raw: "oneof a|b", name: "oneof", arg: "a|b"}},
+				{{raw: "regexp '^(x|y)$'", name: "regexp", arg: "^(x|y)$"}, {raw: "len>0", name: "len", op: ">", arg: "0"}},
+			}},
+		}},
+	}
+	for _, tc := range testCases {
+		dirs, err := parseTag(tc.tag)
+		if assert.NoError(t, err, tc.tag) {
+			assert.Equal(t, tc.dirs, dirs, tc.tag)
+		}
+	}
+}
+
+func TestTag_ParseErrors(t *testing.T) {
+	_, err := parseTag("required,regexp '^[a-z]{2,5}$")
+	assert.ErrorContains(t, err, "unterminated quote at column 17")
+
+	_, err = parseTag("regexp '^[a-z]'x,required")
+	assert.ErrorContains(t, err, "unexpected 'x' after closing quote at column 16")
+
+	_, err = parseTag("required@")
+	assert.ErrorContains(t, err, "invalid groups at column 9")
+
+	_, err = parseTag("(len==0@create | len==5)")
+	assert.ErrorContains(t, err, "directive 'len==0' inside '(len==0@create | len==5)' of tag '(len==0@create | len==5)' cannot be limited to groups")
+
+	_, err = parseTag("!(len==0 | !len==5@create)")
+	assert.ErrorContains(t, err, "directive '!len==5' inside '(len==0 | !len==5@create)'")
+
+	_, err = parseTag("(len==0 | len==5")
+	assert.ErrorContains(t, err, "unterminated parenthesis at column 1")
+
+	_, err = parseTag("(len==0 | )")
+	assert.ErrorContains(t, err, "empty alternative")
+
+	_, err = parseTag("(len==0 | len==5)x")
+	assert.ErrorContains(t, err, "unexpected 'x' after closing parenthesis at column 18")
+
+	_, err = parseTag("!,required")
+	assert.ErrorContains(t, err, "expected directive after '!' at column 1")
+
+	_, err = parseTag("required,<=5")
+	assert.ErrorContains(t, err, "expected directive name at column 10")
+
+	_, err = parseTag("default='x'@create|")
+	assert.ErrorContains(t, err, "invalid groups at column 12")
+}
+
+func TestTag_SplitArg(t *testing.T) {
+	assert.Equal(t, []string{"a", "b", "c"}, splitArg("a|b|c", '|'))
+	assert.Equal(t, []string{"a|b", "c"}, splitArg("a\\|b|c", '|'))
+	assert.Equal(t, []string{""}, splitArg("", '|'))
+	assert.Equal(t, []string{"a\\b"}, splitArg("a\\b", '|'))
+}
+
+func TestTag_Quoting(t *testing.T) {
+	x := struct {
+		S string `dv8:"regexp '^[a-z]{2,5}$',tolower"`
+		N string `dv8:"oneof 'Smith, John|Doe, Jane|A\\|B',default='Doe, Jane'"`
+	}{
+		S: "ABC",
+	}
+	err := Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "abc", x.S)
+	assert.Equal(t, "Doe, Jane", x.N)
+
+	x.S = "abcdef"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "pattern")
+
+	x.S = "abc"
+	x.N = "A|B"
+	err = Validate(&x)
+	assert.NoError(t, err)
+
+	x.N = "Smith"
+	err = Validate(&x)
+	assert.ErrorContains(t, err, "one of")
+
+	y := struct {
+		S string `dv8:"required,regexp '^[a-z]"`
+	}{
+		S: "abc",
+	}
+	err = Validate(&y)
+	assert.ErrorContains(t, err, "S: unterminated quote at column 17")
+}
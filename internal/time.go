@@ -19,76 +19,96 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
-	"strings"
 	"time"
 )
 
-// validateTime validates the value of a time against the tags.
-func validateTime(refVal reflect.Value, tags []string) (err error) {
-	i := refVal.Interface().(time.Time)
+// timeDirectives are the directives applicable to a time.
+var timeDirectives = []string{"required", "optional", "default", "val"}
+
+// compileTime compiles the directives that apply to a time.
+func compileTime(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if i.IsZero() && strings.HasPrefix(t, "default=") {
-			def, err := parseTime(t[len("default="):])
+		case "default":
+			def, err := parseTime(d.arg)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if !def.Equal(i) {
-				i = def
-				changed = true
+			if def.IsZero() {
+				continue
 			}
+			defVal := reflect.ValueOf(def)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if !refVal.Interface().(time.Time).IsZero() {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.Set(reflect.ValueOf(i))
-	}
-	if i.IsZero() && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, constraint{
+			directive: "required",
+			check: func(refVal reflect.Value) error {
+				if refVal.Interface().(time.Time).IsZero() {
+					return errors.New("non-zero value is required")
+				}
+				return nil
+			},
+		})
 	}
 	// Range constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<2006-01-02
-			operator := t[3:4]
-			var v time.Time
-			if t[4] == '=' {
-				operator += "="
-				v, err = parseTime(t[5:])
-			} else {
-				v, err = parseTime(t[4:])
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "<=" && i.After(v):
-				err = fmt.Errorf("must be earlier than or equal to %v", v)
-			case operator == "<" && !i.Before(v):
-				err = fmt.Errorf("must be earlier than %v", v)
-			case operator == ">=" && i.Before(v):
-				err = fmt.Errorf("must be later than or equal to %v", v)
-			case operator == ">" && !i.After(v):
-				err = fmt.Errorf("must be later than %v", v)
-			case operator == "!=" && i.Equal(v):
-				err = fmt.Errorf("must not equal %v", v)
-			case operator == "==" && !i.Equal(v):
-				err = fmt.Errorf("must equal %v", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val<2006-01-02
+		operator := d.op
+		v, err := parseTime(d.arg)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				i := refVal.Interface().(time.Time)
+				switch {
+				case operator == "<=" && i.After(v):
+					return fmt.Errorf("must be earlier than or equal to %v", v)
+				case operator == "<" && !i.Before(v):
+					return fmt.Errorf("must be earlier than %v", v)
+				case operator == ">=" && i.Before(v):
+					return fmt.Errorf("must be later than or equal to %v", v)
+				case operator == ">" && !i.After(v):
+					return fmt.Errorf("must be later than %v", v)
+				case operator == "!=" && i.Equal(v):
+					return fmt.Errorf("must not equal %v", v)
+				case operator == "==" && !i.Equal(v):
+					return fmt.Errorf("must equal %v", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
 
 func parseTime(value string) (time.Time, error) {
@@ -142,3 +142,15 @@ func TestTime_Val(t *testing.T) {
 	err = Validate(&bad)
 	assert.ErrorContains(t, err, "operator")
 }
+
+func TestTime_Optional(t *testing.T) {
+	x := struct {
+		T time.Time `dv8:"optional,val>=2000-01-01T00:00:00Z"`
+	}{}
+	err := Validate(&x)
+	assert.NoError(t, err)
+
+	x.T = time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+	err = Validate(&x)
+	assert.Error(t, err)
+}
@@ -0,0 +1,73 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// TypeFunc validates a value of a registered type.
+// It receives a pointer to the value and the dv8 tag of the field.
+type TypeFunc func(ctx context.Context, ptr any, tags string) error
+
+var (
+	typeFuncs    = map[reflect.Type]TypeFunc{}
+	typeFuncsMux sync.RWMutex
+)
+
+// RegisterType registers a function that validates all values of the type,
+// in place of the directives that DV8 otherwise applies based on the kind of the type.
+// Registering a type that is already registered replaces its function.
+func RegisterType(refType reflect.Type, fn TypeFunc) error {
+	if refType == nil {
+		return errors.New("missing type")
+	}
+	if fn == nil {
+		return errors.New("missing function of type '" + refType.String() + "'")
+	}
+	typeFuncsMux.Lock()
+	typeFuncs[refType] = fn
+	typeFuncsMux.Unlock()
+	resetPlans()
+	return nil
+}
+
+// typeFunc returns the function of the registered type, or nil if the type is not registered.
+func typeFunc(refType reflect.Type) TypeFunc {
+	typeFuncsMux.RLock()
+	defer typeFuncsMux.RUnlock()
+	return typeFuncs[refType]
+}
+
+// validateType validates the value of a registered type by calling its function.
+// Values that are not addressable are copied, as are all values in dry run mode, so changes made by the function are not retained.
+func validateType(w *walk, p *plan, refVal reflect.Value) error {
+	if !refVal.CanInterface() {
+		return nil
+	}
+	var ptr reflect.Value
+	if refVal.CanAddr() && !w.opts.DryRun {
+		w.record(refVal)
+		ptr = refVal.Addr()
+	} else {
+		ptr = reflect.New(p.refType)
+		ptr.Elem().Set(refVal)
+	}
+	return p.typeFunc(w.ctx, ptr.Interface(), p.tags)
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestType_Register(t *testing.T) {
+	var tags []string
+	err := RegisterType(reflect.TypeOf(netip.Addr{}), func(ctx context.Context, ptr any, tag string) error {
+		tags = append(tags, tag)
+		addr := ptr.(*netip.Addr)
+		if tag == "required" && !addr.IsValid() {
+			return errors.New("address is required")
+		}
+		if addr.IsValid() && !addr.Is4() {
+			return errors.New("IPv4 address is required")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Error(t, RegisterType(reflect.TypeOf(netip.Addr{}), nil))
+	assert.Error(t, RegisterType(nil, nil))
+
+	type host struct {
+		Addr    netip.Addr   `dv8:"required"`
+		Backup  *netip.Addr  `dv8:"required"`
+		Aliases []netip.Addr `dv8:"arrlen<=2"`
+	}
+	backup := netip.MustParseAddr("10.0.0.2")
+	h := &host{
+		Addr:    netip.MustParseAddr("10.0.0.1"),
+		Backup:  &backup,
+		Aliases: []netip.Addr{netip.MustParseAddr("10.0.0.3")},
+	}
+	err = Validate(h)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"required", "required", ""}, tags)
+
+	h.Aliases = append(h.Aliases, netip.MustParseAddr("::1"))
+	err = Validate(h)
+	assert.ErrorContains(t, err, "Aliases: [1]: IPv4 address is required")
+
+	h.Addr = netip.Addr{}
+	err = Validate(h)
+	assert.ErrorContains(t, err, "Addr: address is required")
+
+	// Directives are passed as they are, even in strict mode
+	err = ValidateOptions(context.Background(), &host{Backup: &backup}, Options{Strict: true})
+	assert.ErrorContains(t, err, "Addr: address is required")
+}
+
+func TestType_Modify(t *testing.T) {
+	type counter struct {
+		N int
+	}
+	err := RegisterType(reflect.TypeOf(counter{}), func(ctx context.Context, ptr any, tag string) error {
+		ptr.(*counter).N++
+		return nil
+	})
+	assert.NoError(t, err)
+
+	type data struct {
+		Counter  counter
+		Counters map[string]counter
+	}
+	d := &data{Counters: map[string]counter{"a": {N: 5}}}
+	err = Validate(d)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, d.Counter.N)
+	assert.Equal(t, 6, d.Counters["a"].N)
+}
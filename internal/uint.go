@@ -16,77 +16,88 @@ limitations under the License.
 package internal
 
 import (
-	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
-	"strings"
 )
 
-// validateUint validates the value of an unsigned integer against the tags.
-func validateUint(refVal reflect.Value, tags []string) (err error) {
-	i := refVal.Uint()
+// uintDirectives are the directives applicable to an unsigned integer.
+var uintDirectives = []string{"required", "optional", "default", "val"}
+
+// compileUint compiles the directives that apply to an unsigned integer.
+func compileUint(p *plan, dirs []directive) error {
+	var errs Errors
 	// Default value and required
 	required := false
-	changed := false
-	for _, t := range tags {
-		if t == "required" {
+	for _, d := range dirs {
+		switch d.name {
+		case "required":
 			required = true
-		} else if i == 0 && strings.HasPrefix(t, "default=") {
-			def, err := strconv.ParseUint(t[len("default="):], 10, 64)
+		case "default":
+			def, err := strconv.ParseUint(d.arg, 10, 64)
 			if err != nil {
-				return err
+				errs = append(errs, compileError(d, err))
+				continue
 			}
-			if def != i {
-				i = def
-				changed = true
+			if def == 0 {
+				continue
 			}
+			defVal := reflect.ValueOf(def).Convert(p.refType)
+			p.normalizers = append(p.normalizers, normalizer{
+				directive: d.name,
+				apply: func(refVal reflect.Value) (reflect.Value, bool) {
+					if refVal.Uint() != 0 {
+						return refVal, false
+					}
+					return defVal, true
+				},
+			})
 		}
 	}
-	if changed {
-		if !refVal.CanSet() {
-			return errors.New("data must be passed by reference")
-		}
-		refVal.SetUint(i)
-	}
-	if i == 0 && required {
-		return errors.New("non-zero value is required")
+	if required {
+		p.constraints = append(p.constraints, requiredConstraint("non-zero value is required", reflect.Value.IsZero))
 	}
 	// Range constraints
-	for _, t := range tags {
-		if strings.HasPrefix(t, "val") && len(t) > 4 {
-			// Example: val<M
-			operator := t[3:4]
-			var v uint64
-			if t[4] == '=' {
-				operator += "="
-				v, err = strconv.ParseUint(t[5:], 10, 64)
-			} else {
-				v, err = strconv.ParseUint(t[4:], 10, 64)
-			}
-			if err != nil {
-				return err
-			}
-			switch {
-			case operator == "<=" && i > v:
-				err = fmt.Errorf("must be less than or equal to %d", v)
-			case operator == "<" && i >= v:
-				err = fmt.Errorf("must be less than %d", v)
-			case operator == ">=" && i < v:
-				err = fmt.Errorf("must be greater than or equal to %d", v)
-			case operator == ">" && i <= v:
-				err = fmt.Errorf("must be greater than %d", v)
-			case operator == "!=" && i == v:
-				err = fmt.Errorf("must not equal %d", v)
-			case operator == "==" && i != v:
-				err = fmt.Errorf("must equal %d", v)
-			case operator != "<=" && operator != "<" && operator != ">=" && operator != ">" && operator != "!=" && operator != "==":
-				err = fmt.Errorf("unsupported operator '%s'", operator)
-			}
-			if err != nil {
-				return err
-			}
+	for _, d := range dirs {
+		if d.name != "val" {
+			continue
+		}
+		// Example: val<M
+		operator := d.op
+		v, err := strconv.ParseUint(d.arg, 10, 64)
+		if err != nil {
+			errs = append(errs, compileError(d, err))
+			continue
+		}
+		switch operator {
+		case "<=", "<", ">=", ">", "!=", "==":
+		default:
+			errs = append(errs, compileError(d, fmt.Errorf("unsupported operator '%s'", operator)))
+			continue
 		}
+		p.constraints = append(p.constraints, constraint{
+			directive: d.name,
+			operator:  operator,
+			param:     d.arg,
+			check: func(refVal reflect.Value) error {
+				i := refVal.Uint()
+				switch {
+				case operator == "<=" && i > v:
+					return fmt.Errorf("must be less than or equal to %d", v)
+				case operator == "<" && i >= v:
+					return fmt.Errorf("must be less than %d", v)
+				case operator == ">=" && i < v:
+					return fmt.Errorf("must be greater than or equal to %d", v)
+				case operator == ">" && i <= v:
+					return fmt.Errorf("must be greater than %d", v)
+				case operator == "!=" && i == v:
+					return fmt.Errorf("must not equal %d", v)
+				case operator == "==" && i != v:
+					return fmt.Errorf("must equal %d", v)
+				}
+				return nil
+			},
+		})
 	}
-	return nil
+	return errs.join()
 }
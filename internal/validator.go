@@ -18,20 +18,176 @@ package internal
 import (
 	"context"
 	"reflect"
+	"strings"
 )
 
 // Validate takes in a reference to a data struct (pointer, map of, slice of)
 // and validates each of its fields against their dv8 field tags.
 // It recurses into nested structs.
 func Validate(data any) error {
-	return validateAny(context.Background(), reflect.TypeOf(data), reflect.ValueOf(data), nil)
+	w := &walk{ctx: context.Background()}
+	return w.validate(data)
 }
 
 // ValidateContext takes in a reference to a data struct (pointer, map of, slice of)
 // and validates each of its fields against their dv8 field tags.
 // It recurses into nested structs.
 func ValidateContext(ctx context.Context, data any) error {
-	return validateAny(ctx, reflect.TypeOf(data), reflect.ValueOf(data), nil)
+	w := &walk{ctx: ctx}
+	return w.validate(data)
+}
+
+// ValidateAll is the same as ValidateContext but rather than stopping at the first failure,
+// it validates and normalizes the entire data and returns all failures as Errors.
+func ValidateAll(ctx context.Context, data any) error {
+	w := &walk{ctx: ctx, opts: Options{All: true}}
+	err := w.validate(data)
+	if err != nil {
+		return Errors(nil).collect(err)
+	}
+	return nil
+}
+
+// ValidateOptions is the same as ValidateContext but takes in options that control the validation.
+func ValidateOptions(ctx context.Context, data any, opts Options) error {
+	w := &walk{ctx: ctx, opts: opts}
+	err := w.validate(data)
+	if err == nil {
+		return nil
+	}
+	if opts.Atomic {
+		w.rollback()
+	}
+	return w.finish(err)
+}
+
+// finish collects the errors and sets the style in which their paths are rendered, as per the options.
+func (w *walk) finish(err error) error {
+	if w.opts.All {
+		err = Errors(nil).collect(err)
+	}
+	if w.opts.PathStyle != PathStyleDefault || w.opts.PathTagKey != "" {
+		err = withPathStyle(err, w.opts.PathStyle, w.opts.PathTagKey)
+	}
+	return err
+}
+
+// Check returns the errors in the dv8 tags of the type and of the types nested in it, regardless of any value.
+// Tags are checked as in strict mode.
+func Check(refType reflect.Type) error {
+	if refType == nil {
+		return nil
+	}
+	return planOf(refType, nil, planOptions{strict: true, groups: "*"}).err
+}
+
+// Options control the validation.
+type Options struct {
+	All    bool // Collect all errors rather than stop at the first
+	Strict bool // Reject unknown directives, directives not applicable to the type of the field, and conflicting directives
+	DryRun bool // Validate the normalized values without setting them
+	Atomic bool // Restore the original values if the validation fails
+
+	Fields []string // Paths of the only fields to validate, e.g. "Address.Zip" or "Items[*].Qty"
+	Groups []string // Active validation groups, e.g. "create". Directives that are not limited to groups apply to all groups
+
+	TagKeys []string // Tag keys to look up in order, e.g. "dv8" and "validate". Defaults to "dv8"
+	NoTrim  bool     // Do not trim strings unless the trim directive is present
+
+	PathStyle  PathStyle // Style in which the paths of errors are rendered
+	PathTagKey string    // Tag key of the names of fields in the paths of errors, e.g. "json"
+}
+
+// planOptions returns the options that affect the compilation of plans.
+func (opts Options) planOptions() planOptions {
+	return planOptions{
+		strict:  opts.Strict,
+		tagKeys: strings.Join(opts.TagKeys, ","),
+		noTrim:  opts.NoTrim,
+		groups:  strings.Join(opts.Groups, ","),
+	}
+}
+
+// walk holds the state of a single validation pass.
+type walk struct {
+	ctx       context.Context
+	opts      Options
+	mutations []mutation // Mutations to roll back in atomic mode
+
+	sel *selection // Selection of the fields to validate, or nil to validate all
+
+	normalizing bool // Only normalize, without calling custom directives or validators, to compute a normalized copy
+
+	report  bool     // Report the changes made by normalizations
+	path    Path     // Path to the value being validated, when reporting changes
+	changes []Change // Changes made by normalizations, when reporting changes
+}
+
+// mutation is a change made to the data during validation.
+type mutation struct {
+	refVal reflect.Value // Value that was set, or the map whose item was set
+	key    reflect.Value // Key of the item in the map, if applicable
+	old    reflect.Value // Original value
+}
+
+// set sets the value, recording the original value in atomic mode.
+func (w *walk) set(refVal reflect.Value, val reflect.Value) {
+	w.record(refVal)
+	refVal.Set(val)
+}
+
+// setMapIndex sets the value of the item of the map, recording the original value in atomic mode.
+func (w *walk) setMapIndex(refVal reflect.Value, key reflect.Value, val reflect.Value) {
+	if w.opts.Atomic {
+		w.mutations = append(w.mutations, mutation{
+			refVal: refVal,
+			key:    key,
+			old:    refVal.MapIndex(key),
+		})
+	}
+	refVal.SetMapIndex(key, val)
+}
+
+// record records the original value in atomic mode, ahead of a change that may be made by a custom function.
+func (w *walk) record(refVal reflect.Value) {
+	if !w.opts.Atomic || !refVal.CanSet() {
+		return
+	}
+	old := reflect.New(refVal.Type()).Elem()
+	old.Set(refVal)
+	w.mutations = append(w.mutations, mutation{
+		refVal: refVal,
+		old:    old,
+	})
+}
+
+// rollback restores the original values, in reverse order of their change.
+func (w *walk) rollback() {
+	for i := len(w.mutations) - 1; i >= 0; i-- {
+		m := w.mutations[i]
+		if m.key.IsValid() {
+			m.refVal.SetMapIndex(m.key, m.old)
+		} else {
+			m.refVal.Set(m.old)
+		}
+	}
+	w.mutations = nil
+}
+
+// validate validates the data against the plan of its type.
+func (w *walk) validate(data any) error {
+	if data == nil {
+		return nil
+	}
+	refVal := reflect.ValueOf(data)
+	if len(w.opts.Fields) > 0 {
+		sel, err := selectFields(refVal.Type(), w.opts.Fields, w.opts.PathTagKey)
+		if err != nil {
+			return err
+		}
+		w.sel = sel
+	}
+	return validateAny(w, planOf(refVal.Type(), nil, w.opts.planOptions()), refVal)
 }
 
 // Validator implements a single method that returns an error if a struct is invalid.
@@ -18,6 +18,8 @@ package internal
 import (
 	"context"
 	"errors"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -210,3 +212,294 @@ func Test_ReferenceTypes(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Mammal", p.Kind)
 }
+
+func Test_DryRun(t *testing.T) {
+	type pet struct {
+		Name  string            `dv8:"required,len<=8,toupper"`
+		Kind  string            `dv8:"default=Mammal,oneof Mammal|Bird"`
+		Age   int               `dv8:"default=1,val>0"`
+		Tags  map[string]string `dv8:"tolower"`
+		Label any               `dv8:"len<=8"`
+		Owner *Animal
+	}
+	dryRun := Options{DryRun: true}
+	ctx := context.Background()
+
+	x := pet{
+		Name:  " Rex ",
+		Tags:  map[string]string{"a": "FOO"},
+		Label: " Good Boy ",
+		Owner: &Animal{Name: "Jane"},
+	}
+	// Passed by value
+	err := ValidateOptions(ctx, x, dryRun)
+	assert.NoError(t, err)
+	err = Validate(x)
+	assert.ErrorContains(t, err, "reference")
+
+	// Passed by reference
+	err = ValidateOptions(ctx, &x, dryRun)
+	assert.NoError(t, err)
+	assert.Equal(t, " Rex ", x.Name)
+	assert.Equal(t, "", x.Kind)
+	assert.Equal(t, 0, x.Age)
+	assert.Equal(t, "FOO", x.Tags["a"])
+	assert.Equal(t, " Good Boy ", x.Label)
+	assert.Equal(t, "", x.Owner.Kind)
+
+	// Constraints are validated against the normalized values
+	x.Name = " Supercalifragilistic "
+	err = ValidateOptions(ctx, &x, dryRun)
+	assert.ErrorContains(t, err, "Name: length")
+	assert.Equal(t, " Supercalifragilistic ", x.Name)
+
+	x.Name = "   "
+	err = ValidateOptions(ctx, &x, dryRun)
+	assert.ErrorContains(t, err, "Name: value is required")
+
+	x.Name = "Rex"
+	x.Kind = "Fish"
+	err = ValidateOptions(ctx, &x, dryRun)
+	assert.ErrorContains(t, err, "Kind: value must be one of")
+}
+
+type dryRunCoupon struct {
+	Code string `dv8:"trim,toupper"`
+}
+
+func (c *dryRunCoupon) Validate() error {
+	if c.Code != strings.ToUpper(c.Code) {
+		return errors.New("code must be uppercase")
+	}
+	c.Code = "VALIDATED"
+	return nil
+}
+
+func Test_DryRunValidators(t *testing.T) {
+	ctx := context.Background()
+	x := dryRunCoupon{Code: " save10 "}
+	// The validator sees the normalized values and its changes are discarded
+	err := ValidateOptions(ctx, &x, Options{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, " save10 ", x.Code)
+
+	y := []dryRunCoupon{{Code: "off5"}}
+	err = ValidateOptions(ctx, y, Options{DryRun: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "off5", y[0].Code)
+
+	err = Validate(&x)
+	assert.NoError(t, err)
+	assert.Equal(t, "VALIDATED", x.Code)
+}
+
+func Test_Atomic(t *testing.T) {
+	type pet struct {
+		Name  string            `dv8:"required,toupper"`
+		Kind  string            `dv8:"default=Mammal"`
+		Tags  map[string]string `dv8:"tolower"`
+		Label any               `dv8:"len<=8"`
+		Owner *Animal
+		Age   int `dv8:"val>0"`
+	}
+	atomic := Options{Atomic: true}
+	ctx := context.Background()
+
+	x := pet{
+		Name:  " Rex ",
+		Tags:  map[string]string{"a": "FOO"},
+		Label: " Good ",
+		Owner: &Animal{Name: "Jane"},
+	}
+	err := ValidateOptions(ctx, &x, atomic)
+	assert.ErrorContains(t, err, "Age: must be greater")
+	assert.Equal(t, " Rex ", x.Name)
+	assert.Equal(t, "", x.Kind)
+	assert.Equal(t, "FOO", x.Tags["a"])
+	assert.Equal(t, " Good ", x.Label)
+	assert.Equal(t, "", x.Owner.Kind)
+
+	// Also when collecting all errors
+	atomic.All = true
+	err = ValidateOptions(ctx, &x, atomic)
+	assert.ErrorContains(t, err, "Age: must be greater")
+	assert.Equal(t, " Rex ", x.Name)
+	assert.Equal(t, "", x.Owner.Kind)
+
+	// Fully normalized on success
+	x.Age = 5
+	err = ValidateOptions(ctx, &x, atomic)
+	assert.NoError(t, err)
+	assert.Equal(t, "REX", x.Name)
+	assert.Equal(t, "Mammal", x.Kind)
+	assert.Equal(t, "foo", x.Tags["a"])
+	assert.Equal(t, "Good", x.Label)
+	assert.Equal(t, "Mammal", x.Owner.Kind)
+
+	// Partially normalized when not atomic
+	x.Name = " Rex "
+	x.Age = 0
+	err = Validate(&x)
+	assert.Error(t, err)
+	assert.Equal(t, "REX", x.Name)
+}
+
+func Test_ValidateAll(t *testing.T) {
+	type signup struct {
+		First string   `dv8:"required,len<=8"`
+		Last  string   `dv8:"required"`
+		Age   int      `dv8:"val>=18"`
+		Zip   string   `dv8:"required,regexp ^[0-9]{5}$"`
+		Kind  string   `dv8:"default=Mammal"`
+		Tags  []string `dv8:"arrlen<=2,len>0"`
+	}
+	x := signup{
+		First: " Supercalifragilistic",
+		Age:   16,
+		Zip:   " 12345 ",
+		Tags:  []string{"a", "", "c"},
+	}
+	err := ValidateAll(context.Background(), &x)
+	if assert.Error(t, err) {
+		var errs Errors
+		assert.True(t, errors.As(err, &errs))
+		assert.Len(t, errs, 5)
+		assert.ErrorContains(t, errs[0], "First: length")
+		assert.ErrorContains(t, errs[1], "Last: value is required")
+		assert.ErrorContains(t, errs[2], "Age: must be greater")
+		assert.ErrorContains(t, errs[3], "Tags: length")
+		assert.ErrorContains(t, errs[4], "Tags: [1]: length")
+	}
+	// Normalization continues past failing fields
+	assert.Equal(t, "12345", x.Zip)
+	assert.Equal(t, "Mammal", x.Kind)
+
+	x.First = "Jane"
+	x.Last = "Doe"
+	x.Age = 18
+	x.Tags = []string{"a"}
+	err = ValidateAll(context.Background(), &x)
+	assert.NoError(t, err)
+
+	// Validator interface failures are collected too
+	p := Person{
+		Name: "Fail Validate",
+		Zip:  "12345",
+		Age:  18,
+	}
+	err = ValidateAll(context.Background(), []Person{p, p})
+	if assert.Error(t, err) {
+		assert.Len(t, err.(Errors), 2)
+		assert.ErrorContains(t, err.(Errors)[1], "[1]: Validate")
+	}
+}
+
+func Test_FieldError(t *testing.T) {
+	type group struct {
+		Names []string          `dv8:"len>0,len<=8"`
+		Index map[string]string `dv8:"regexp ^[a-z]+$"`
+		Kind  string            `dv8:"oneof A|B"`
+		Lead  *Person           `dv8:"required"`
+	}
+	x := group{
+		Names: []string{"John", "Paul", "Supercalifragilistic"},
+	}
+	err := Validate(&x)
+	var fe *FieldError
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Names: [2]", fe.Path.String())
+		assert.Equal(t, "len", fe.Directive)
+		assert.Equal(t, "<=", fe.Operator)
+		assert.Equal(t, "8", fe.Param)
+		assert.Equal(t, "Supercalifragilistic", fe.Value)
+		assert.Equal(t, "Names: [2]: length must be less than or equal to 8", fe.Error())
+	}
+
+	x.Names = nil
+	x.Index = map[string]string{"k": "ABC"}
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Index: [k]", fe.Path.String())
+		assert.Equal(t, "regexp", fe.Directive)
+		assert.Equal(t, "^[a-z]+$", fe.Param)
+		assert.Equal(t, "ABC", fe.Value)
+	}
+
+	x.Index = nil
+	x.Kind = "C"
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Kind", fe.Path.String())
+		assert.Equal(t, "oneof", fe.Directive)
+		assert.Equal(t, "A|B", fe.Param)
+	}
+
+	x.Kind = "A"
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Lead", fe.Path.String())
+		assert.Equal(t, "required", fe.Directive)
+	}
+
+	// Errors of custom validators are wrapped
+	x.Lead = &Person{
+		Name: "Fail Validate",
+		Zip:  "12345",
+		Age:  18,
+	}
+	err = Validate(&x)
+	if assert.True(t, errors.As(err, &fe)) {
+		assert.Equal(t, "Lead", fe.Path.String())
+		assert.Equal(t, "", fe.Directive)
+		assert.Equal(t, "Lead: Validate", fe.Error())
+	}
+}
+
+func Test_Check(t *testing.T) {
+	type item struct {
+		SKU string `dv8:"required,regexp '^[A-Z'"`
+		Qty int    `dv8:"val>abc,default=x,val<<5"`
+	}
+	type key struct {
+		ID int `dv8:"main"`
+	}
+	type order struct {
+		ID       key              `dv8:"regexp ^[0-9]+$"`
+		Items    []item           `dv8:"arrlen>0"`
+		Backup   *item            // Same type, reported once
+		Notes    map[string]*item `dv8:"maplen<=x"`
+		Comment  string           `dv8:"notrim,len<=abc"`
+		Internal string           `dv8:"-"`
+	}
+
+	err := Check(reflect.TypeOf(order{}))
+	if assert.Error(t, err) {
+		errs, ok := err.(Errors)
+		if assert.True(t, ok) && assert.Len(t, errs, 7) {
+			assert.ErrorContains(t, errs[0], "field 'ID' of")
+			assert.ErrorContains(t, errs[0], "'regexp ^[0-9]+$' is not applicable to 'int'")
+			assert.ErrorContains(t, errs[1], "field 'SKU' of")
+			assert.ErrorContains(t, errs[1], "error parsing regexp")
+			assert.ErrorContains(t, errs[2], "field 'Qty' of")
+			assert.ErrorContains(t, errs[2], "parsing \"x\"")
+			assert.ErrorContains(t, errs[3], "field 'Qty' of")
+			assert.ErrorContains(t, errs[3], "parsing \"abc\"")
+			assert.ErrorContains(t, errs[4], "field 'Qty' of")
+			assert.ErrorContains(t, errs[4], "parsing \"<5\"")
+			assert.ErrorContains(t, errs[5], "field 'Notes' of")
+			assert.ErrorContains(t, errs[5], "parsing \"x\"")
+			assert.ErrorContains(t, errs[6], "field 'Comment' of")
+			assert.ErrorContains(t, errs[6], "parsing \"abc\"")
+		}
+	}
+
+	// The same errors are reported for pointers and nested types
+	err = Check(reflect.TypeOf(&order{}))
+	assert.Len(t, err.(Errors), 7)
+	err = Check(reflect.TypeOf([]item{}))
+	assert.Len(t, err.(Errors), 4)
+
+	assert.NoError(t, Check(reflect.TypeOf(Person{})))
+	assert.NoError(t, Check(reflect.TypeOf(Directory{})))
+	assert.NoError(t, Check(nil))
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2023-2024 Microbus LLC and various contributors
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dv8
+
+import (
+	"context"
+
+	"github.com/microbus-io/dv8/internal"
+)
+
+/*
+TypedValidator validates values of type T using the options it was created with.
+It is safe for concurrent use, so it is typically created once and reused.
+The name Validator is taken by the interface that types implement to define custom validations.
+*/
+type TypedValidator[T any] struct {
+	opts internal.Options
+}
+
+/*
+For returns a validator of values of type T, configured with the given options.
+Validators with different options can be used side by side.
+Strict mode is on by default, and can be turned off with Strict(false).
+
+Example:
+
+	var personValidator = dv8.For[Person](
+		dv8.CollectAll(true),
+		dv8.ErrorPathStyle(dv8.PathStyleJSONPointer, "json"),
+	)
+
+	err := personValidator.Validate(&p)
+*/
+func For[T any](opts ...Option) *TypedValidator[T] {
+	v := &TypedValidator[T]{
+		opts: internal.Options{
+			Strict: true,
+		},
+	}
+	for _, opt := range opts {
+		opt(&v.opts)
+	}
+	return v
+}
+
+// Validate validates the fields of the value against their dv8 field tags, normalizing them as needed.
+func (v *TypedValidator[T]) Validate(data *T) error {
+	return v.ValidateContext(context.Background(), data)
+}
+
+// ValidateContext is the same as Validate but takes in a context that is used to validate structs
+// that implement the ValidatorContext interface.
+func (v *TypedValidator[T]) ValidateContext(ctx context.Context, data *T) error {
+	if data == nil {
+		return nil
+	}
+	return internal.ValidateOptions(ctx, data, v.opts)
+}
+
+// ValidateSlice validates each of the values in the slice.
+// The paths of errors are prefixed by the index of the value in the slice.
+func (v *TypedValidator[T]) ValidateSlice(data []T) error {
+	return v.ValidateSliceContext(context.Background(), data)
+}
+
+// ValidateSliceContext is the same as ValidateSlice but takes in a context that is used to validate structs
+// that implement the ValidatorContext interface.
+func (v *TypedValidator[T]) ValidateSliceContext(ctx context.Context, data []T) error {
+	return internal.ValidateOptions(ctx, data, v.opts)
+}
+
+// Normalized returns a normalized and validated copy of the value, leaving the original untouched.
+// The copy is deep, so that pointers, slices, maps and interfaces nested in the value are copied as well.
+func (v *TypedValidator[T]) Normalized(data T) (T, error) {
+	return v.NormalizedContext(context.Background(), data)
+}
+
+// NormalizedContext is the same as Normalized but takes in a context that is used to validate structs
+// that implement the ValidatorContext interface.
+func (v *TypedValidator[T]) NormalizedContext(ctx context.Context, data T) (T, error) {
+	cp := internal.DeepCopy(&data).(*T)
+	err := internal.ValidateOptions(ctx, cp, v.opts)
+	return *cp, err
+}
@@ -17,6 +17,7 @@ package dv8
 
 import (
 	"context"
+	"reflect"
 
 	"github.com/microbus-io/dv8/internal"
 )
@@ -71,6 +72,412 @@ func ValidateContext(ctx context.Context, data ...any) error {
 	return nil
 }
 
+/*
+ValidateAll is the same as Validate but rather than stopping at the first failure,
+it continues to validate and normalize the entire data.
+All failures are returned in an Errors list, each prefixed with the path of the field.
+
+Example:
+
+	err := dv8.ValidateAll(p)
+	if err != nil {
+		return err // First: length must be less than or equal to 32\nAge: must be less than or equal to 120
+	}
+*/
+func ValidateAll(data ...any) error {
+	return ValidateAllContext(context.Background(), data...)
+}
+
+// ValidateAllContext is the same as ValidateAll but takes in a context that is used to validate structs
+// that implement the ValidatorContext interface.
+func ValidateAllContext(ctx context.Context, data ...any) error {
+	var errs Errors
+	for i := range data {
+		err := internal.ValidateAll(ctx, data[i])
+		if err != nil {
+			errs = append(errs, err.(Errors)...)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+/*
+ValidateFields is the same as Validate but validates and normalizes only the fields at the given paths,
+along with everything they contain.
+It is useful for PATCH requests in which only some of the fields are provided.
+Nested fields are separated by a dot, and the items of an array or map are selected by index or key in brackets,
+or all of them with [*].
+The Validator interface is still called on the structs along the paths.
+
+Example:
+
+	err := dv8.ValidateFields(&order, "Address.Zip", "Items[*].Qty")
+*/
+func ValidateFields(data any, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	return internal.ValidateOptions(context.Background(), data, internal.Options{Fields: paths})
+}
+
+// ValidateWithOptions is the same as ValidateContext but takes in options that control the validation.
+func ValidateWithOptions(ctx context.Context, data any, opts ...Option) error {
+	var options internal.Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return internal.ValidateOptions(ctx, data, options)
+}
+
+/*
+ValidateWithReport is the same as ValidateWithOptions but also returns the changes made to the data by normalizations,
+such as trimming, case conversions and defaults, in order of their occurrence.
+Changes made by custom directives, registered types and Validator methods are not reported.
+In dry run mode, the changes are those that would have been made.
+No changes are returned if they are rolled back in atomic mode.
+
+Example:
+
+	changes, err := dv8.ValidateWithReport(ctx, &p)
+	for _, c := range changes {
+		log.Print(c) // State: default: '' -> 'CA'
+	}
+*/
+func ValidateWithReport(ctx context.Context, data any, opts ...Option) ([]Change, error) {
+	var options internal.Options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return internal.ValidateReport(ctx, data, options)
+}
+
+/*
+Normalized returns a normalized and validated copy of the value, leaving the original untouched.
+The copy is deep, so that pointers, slices, maps and interfaces nested in the value are copied as well.
+It is useful for validating values that must not be modified, such as immutable configuration or values shared by a cache.
+The copy is returned along with the error, if any.
+
+Example:
+
+	cfg, err := dv8.Normalized(cache.Config())
+	if err != nil {
+		return err
+	}
+*/
+func Normalized[T any](data T) (T, error) {
+	cp := internal.DeepCopy(&data).(*T)
+	err := internal.Validate(cp)
+	return *cp, err
+}
+
+// Option controls the validation.
+type Option func(opts *internal.Options)
+
+/*
+Strict rejects tags with unknown directives, directives that are not applicable to the type of the field,
+and conflicting directives.
+Errors in tags are reported as a TagError, or as Errors if there are more than one,
+regardless of the values of the fields.
+
+Example:
+
+	type Person struct {
+		Name string `dv8:"requried"`
+		Age  int    `dv8:"len<=3"`
+	}
+
+	err := dv8.ValidateWithOptions(ctx, &p, dv8.Strict(true))
+	// invalid tag of field 'Name' of 'Person': unknown directive 'requried'
+	// invalid tag of field 'Age' of 'Person': directive 'len<=3' is not applicable to 'int'
+*/
+func Strict(strict bool) Option {
+	return func(opts *internal.Options) {
+		opts.Strict = strict
+	}
+}
+
+/*
+DryRun validates the data as if it were normalized, without modifying it.
+Defaults, trimming and case conversions are computed and validated against, but are not set.
+Data passed by value, or data that is shared and must not be modified, can therefore be validated safely.
+Custom directives and registered types receive values that cannot be set or copies thereof.
+The Validate methods of types that implement the Validator interface are called on normalized copies.
+
+Example:
+
+	err := dv8.ValidateWithOptions(ctx, p, dv8.DryRun(true))
+*/
+func DryRun(dryRun bool) Option {
+	return func(opts *internal.Options) {
+		opts.DryRun = dryRun
+	}
+}
+
+/*
+Atomic restores the original values of the data if the validation fails,
+so that the data is either fully normalized or left untouched.
+Changes made by custom directives and registered types are restored as well,
+but not changes made by the Validate methods of types that implement the Validator interface.
+
+Example:
+
+	err := dv8.ValidateWithOptions(ctx, &p, dv8.Atomic(true))
+	if err != nil {
+		return err // p is unchanged
+	}
+*/
+func Atomic(atomic bool) Option {
+	return func(opts *internal.Options) {
+		opts.Atomic = atomic
+	}
+}
+
+/*
+Fields validates and normalizes only the fields at the given paths, along with everything they contain.
+Fields are matched by their Go name, or by their name in the tag key of ErrorPathStyle, if provided.
+See ValidateFields.
+
+Example:
+
+	err := dv8.ValidateWithOptions(ctx, &order,
+		dv8.Fields("address.zip", "items[*].qty"),
+		dv8.ErrorPathStyle(dv8.PathStyleJSONPointer, "json"),
+	)
+*/
+func Fields(paths ...string) Option {
+	return func(opts *internal.Options) {
+		opts.Fields = paths
+	}
+}
+
+/*
+Groups sets the active validation groups.
+Directives limited to groups with a suffix of @ followed by the names of the groups apply only if one of their groups is active.
+Text arguments, such as those of default, oneof or regexp, must be enclosed in single quotes to be limited to groups.
+Directives that are not limited to groups apply to all groups.
+
+Example:
+
+	type User struct {
+		ID   int    `dv8:"val==0@create,required@update"`
+		Name string `dv8:"required@create,len<=32"`
+	}
+
+	err := dv8.ValidateWithOptions(ctx, &u, dv8.Groups("create"))
+*/
+func Groups(groups ...string) Option {
+	return func(opts *internal.Options) {
+		opts.Groups = groups
+	}
+}
+
+// CollectAll continues the validation past the first failure and returns all failures as Errors.
+func CollectAll(all bool) Option {
+	return func(opts *internal.Options) {
+		opts.All = all
+	}
+}
+
+/*
+ErrorPathStyle renders the paths of errors in the given style.
+If a tag key such as "json", "yaml" or "form" is provided, field names are taken from the corresponding tag.
+
+Example:
+
+	err := dv8.ValidateWithOptions(ctx, &order, dv8.ErrorPathStyle(dv8.PathStyleJSONPointer, "json"))
+	// /items/1/qty: must be greater than 0
+*/
+func ErrorPathStyle(style PathStyle, tagKey string) Option {
+	return func(opts *internal.Options) {
+		opts.PathStyle = style
+		opts.PathTagKey = tagKey
+	}
+}
+
+/*
+TagKeys looks up the directives of fields in the first of the given tag keys that is present on the field.
+It is useful for structs that are shared with other validation libraries.
+Directives are looked up in the dv8 tag if no tag key is provided.
+
+Example:
+
+	type Person struct {
+		Name  string `validate:"required"`
+		Email string `dv8:"required,tolower" validate:"required"`
+	}
+
+	err := dv8.ValidateWithOptions(ctx, &p, dv8.TagKeys("dv8", "validate"))
+*/
+func TagKeys(keys ...string) Option {
+	return func(opts *internal.Options) {
+		opts.TagKeys = keys
+	}
+}
+
+/*
+TrimByDefault determines whether strings are trimmed of leading and trailing whitespaces by default.
+When true, which is the default, strings are trimmed unless the notrim directive is present.
+When false, strings are trimmed only if the trim directive is present.
+
+Example:
+
+	type Credentials struct {
+		User     string `dv8:"required,trim"`
+		Password string `dv8:"required"`
+	}
+
+	err := dv8.ValidateWithOptions(ctx, &c, dv8.TrimByDefault(false))
+*/
+func TrimByDefault(trim bool) Option {
+	return func(opts *internal.Options) {
+		opts.NoTrim = !trim
+	}
+}
+
+/*
+Check returns the errors in the dv8 tags of the type and of the types nested in it, regardless of any value.
+Tags are checked as in strict mode. Errors are reported as a TagError, or as Errors if there are more than one.
+
+Check is useful at init time, or in a unit test, to catch malformed tags before they surface in production.
+
+Example:
+
+	func TestTags(t *testing.T) {
+		err := dv8.Check(reflect.TypeOf(Person{}))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+*/
+func Check(refType reflect.Type) error {
+	return internal.Check(refType)
+}
+
+/*
+MustCheck is the same as Check but panics if the tags of the type have errors.
+
+Example:
+
+	func init() {
+		dv8.MustCheck[Person]()
+	}
+*/
+func MustCheck[T any]() {
+	err := Check(reflect.TypeOf((*T)(nil)).Elem())
+	if err != nil {
+		panic(err)
+	}
+}
+
+/*
+RegisterDirective registers a custom directive that can be used in the dv8 tag of a field of any type,
+alongside the built-in directives.
+The function receives the value of the field, the argument of the directive, and the context of the validation.
+It may normalize the value if it is settable, that is, if the data was passed by reference.
+Custom directives run after the built-in directives of the field, in order of appearance.
+Custom directives placed on an array, map or pointer apply to its elements, as do most built-in directives.
+
+Example:
+
+	dv8.RegisterDirective("sku", func(ctx context.Context, refVal reflect.Value, arg string) error {
+		if !strings.HasPrefix(refVal.String(), "SKU-") {
+			return errors.New("invalid SKU")
+		}
+		return nil
+	})
+
+	type Product struct {
+		SKU string `dv8:"required,len==8,sku"`
+	}
+*/
+func RegisterDirective(name string, fn DirectiveFunc) error {
+	return internal.RegisterDirective(name, fn)
+}
+
+// DirectiveFunc validates a value against a custom directive.
+type DirectiveFunc = internal.DirectiveFunc
+
+/*
+RegisterAlias registers a named bundle of directives that expands in place wherever the alias appears in a tag,
+either by name or prefixed with @. Other directives may appear alongside the alias in the same tag.
+Aliases may refer to other aliases.
+The directives of the alias are validated at the time of registration and must therefore be known,
+including custom directives and other aliases.
+Operators and arguments, such as the length of len or the pattern of regexp, are validated as well.
+
+Example:
+
+	dv8.RegisterAlias("zip", "required,regexp ^[0-9]{5}$")
+
+	type Address struct {
+		Zip       string `dv8:"@zip"`
+		BillToZip string `dv8:"zip,default=94105"`
+	}
+*/
+func RegisterAlias(name string, tags string) error {
+	return internal.RegisterAlias(name, tags)
+}
+
+/*
+RegisterType registers a function that validates all values of type T, wherever they appear in the validated data.
+It is useful for types that are defined in other modules and therefore cannot implement the Validator interface.
+The function receives a pointer to the value and the dv8 tag of the field that holds it, which it interprets on its own.
+It takes the place of the directives that DV8 otherwise applies based on the kind of the type.
+
+Example:
+
+	dv8.RegisterType(func(ctx context.Context, addr *netip.Addr, tags string) error {
+		if tags == "required" && !addr.IsValid() {
+			return errors.New("address is required")
+		}
+		return nil
+	})
+
+	type Host struct {
+		Addr netip.Addr `dv8:"required"`
+	}
+*/
+func RegisterType[T any](fn func(ctx context.Context, v *T, tags string) error) error {
+	refType := reflect.TypeOf((*T)(nil)).Elem()
+	if fn == nil {
+		return internal.RegisterType(refType, nil)
+	}
+	return internal.RegisterType(refType, func(ctx context.Context, ptr any, tags string) error {
+		return fn(ctx, ptr.(*T), tags)
+	})
+}
+
+// TagError is an error in the dv8 tag of a field of a struct, such as an unknown directive.
+type TagError = internal.TagError
+
+// Errors is a list of validation errors returned by ValidateAll.
+type Errors = internal.Errors
+
+// FieldError is a validation failure of a single field.
+// Use errors.As to obtain the path to the field, the directive that failed and the offending value.
+type FieldError = internal.FieldError
+
+// Change is a normalization of a single field made during validation.
+type Change = internal.Change
+
+// Path is the path to a field from the validated value.
+type Path = internal.Path
+
+// PathSegment is a single step in the path to a field.
+type PathSegment = internal.PathSegment
+
+// PathStyle determines how a path is rendered.
+type PathStyle = internal.PathStyle
+
+const (
+	PathStyleDefault     = internal.PathStyleDefault     // Names: [2]
+	PathStyleJSONPointer = internal.PathStyleJSONPointer // /names/2
+	PathStyleDotted      = internal.PathStyleDotted      // names[2]
+)
+
 // Validator implements a single method that returns an error if a struct is invalid.
 // DV8 calls this function during validation on types that implements it.
 type Validator = internal.Validator